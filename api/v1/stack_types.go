@@ -29,6 +29,195 @@ const (
 	Failure   StackStatusCode = "failure"
 )
 
+const (
+	// ConditionReady is True once every enabled component of the stack is installed.
+	ConditionReady string = "Ready"
+	// ConditionProgressing is True while components are being installed or removed.
+	ConditionProgressing string = "Progressing"
+	// ConditionConflict is True when another Stack object already owns a helm release
+	// or namespace the stack would install, nothing gets installed until it is resolved.
+	ConditionConflict string = "Conflict"
+	// ConditionDrifted is True when installed components are missing from the cluster
+	// or their helm releases are not deployed.
+	ConditionDrifted string = "Drifted"
+	// ConditionStalled is True once the stack failed more times in a row than the manager
+	// retries, it is only reconciled again when the Stack changes or a retry is forced.
+	ConditionStalled string = "Stalled"
+	// ConditionPlanned is True while status.plan holds the plan of the current spec.
+	ConditionPlanned string = "Planned"
+	// ConditionPendingApproval is True while the plan of a Stack requiring approval waits for it.
+	ConditionPendingApproval string = "PendingApproval"
+	// ConditionUpdatesAvailable is True while components following an update channel have
+	// a newer release waiting for the maintenance window.
+	ConditionUpdatesAvailable string = "UpdatesAvailable"
+)
+
+// RetryAnnotation makes the manager retry a failing Stack right away, whatever its backoff,
+// retry count or failure policy say. The annotation is removed once picked up.
+const RetryAnnotation string = "app.ksctl.com/retry"
+
+// ApprovedPlanAnnotation approves the plan whose hash it holds for a Stack requiring approval.
+const ApprovedPlanAnnotation string = "app.ksctl.com/approved-plan"
+
+type ComponentPhase string
+
+const (
+	ComponentPending      ComponentPhase = "Pending"
+	ComponentInstalling   ComponentPhase = "Installing"
+	ComponentInstalled    ComponentPhase = "Installed"
+	ComponentUpgrading    ComponentPhase = "Upgrading"
+	ComponentUpdating     ComponentPhase = "Updating"
+	ComponentUninstalling ComponentPhase = "Uninstalling"
+	ComponentUninstalled  ComponentPhase = "Uninstalled"
+	ComponentDisabled     ComponentPhase = "Disabled"
+	ComponentFailed       ComponentPhase = "Failed"
+	ComponentDrifted      ComponentPhase = "Drifted"
+)
+
+// DriftPolicy tells what happens once an installed component no longer matches the cluster.
+// +kubebuilder:validation:Enum=ignore;report;heal
+type DriftPolicy string
+
+const (
+	// DriftIgnore skips the drift checks.
+	DriftIgnore DriftPolicy = "ignore"
+	// DriftReport surfaces the drift in the status.
+	DriftReport DriftPolicy = "report"
+	// DriftHeal reinstalls the drifted components.
+	DriftHeal DriftPolicy = "heal"
+)
+
+// FailurePolicy tells what happens once installing the stack failed.
+// +kubebuilder:validation:Enum=retry;rollback;pause
+type FailurePolicy string
+
+const (
+	// FailureRetry keeps retrying the install.
+	FailureRetry FailurePolicy = "retry"
+	// FailureRollback uninstalls the components installed by the failed attempt, in
+	// reverse order, and waits for the Stack to change.
+	FailureRollback FailurePolicy = "rollback"
+	// FailurePause leaves the cluster as it is and waits for the Stack to change.
+	FailurePause FailurePolicy = "pause"
+)
+
+// StackMode tells whether the manager changes the cluster for the Stack.
+// +kubebuilder:validation:Enum=plan;apply
+type StackMode string
+
+const (
+	// ModePlan only computes what applying the Stack would do and writes it to status.plan.
+	ModePlan StackMode = "plan"
+	// ModeApply changes the cluster, when status.plan was computed for the same spec
+	// exactly the planned steps are carried out.
+	ModeApply StackMode = "apply"
+)
+
+type PlanAction string
+
+const (
+	PlanInstall   PlanAction = "Install"
+	PlanUpgrade   PlanAction = "Upgrade"
+	PlanUninstall PlanAction = "Uninstall"
+	PlanSkip      PlanAction = "Skip"
+)
+
+// UpdateChannel tells which upstream releases a component gets upgraded to on its own.
+// +kubebuilder:validation:Enum=pinned;patch;minor
+type UpdateChannel string
+
+const (
+	// ChannelPinned keeps the component on the version it got installed with.
+	ChannelPinned UpdateChannel = "pinned"
+	// ChannelPatch upgrades to the patch releases of the installed minor version.
+	ChannelPatch UpdateChannel = "patch"
+	// ChannelMinor upgrades to the minor and patch releases of the installed major
+	// version, components still on 0.x only get their patch releases.
+	ChannelMinor UpdateChannel = "minor"
+)
+
+// MaintenanceWindow is when components may get upgraded on their own.
+type MaintenanceWindow struct {
+	// Schedule is the cron expression the window opens on, e.g. "0 2 * * sat".
+	Schedule string `json:"schedule"`
+	// Duration the window stays open for.
+	Duration metav1.Duration `json:"duration"`
+	// TimeZone of the schedule, e.g. "Europe/Berlin", defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// UpdatePolicy makes the components follow update channels. Only components
+// whose version override is unset or "latest" follow one, the webhook then
+// leaves their version unpinned.
+type UpdatePolicy struct {
+	// Channel of the components missing from channels.
+	// +kubebuilder:default=pinned
+	// +optional
+	Channel UpdateChannel `json:"channel,omitempty"`
+	// Channels by component id.
+	// +optional
+	Channels map[string]UpdateChannel `json:"channels,omitempty"`
+	// CheckInterval is how often the version sources get checked for updates.
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+	// MaintenanceWindow holds the upgrades back until it opens, without one
+	// components are upgraded as soon as an update is found.
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+}
+
+// ChannelOf returns the update channel the component follows.
+func (p *UpdatePolicy) ChannelOf(componentId string) UpdateChannel {
+	if p == nil {
+		return ChannelPinned
+	}
+	if c, ok := p.Channels[componentId]; ok && len(c) != 0 {
+		return c
+	}
+	if len(p.Channel) != 0 {
+		return p.Channel
+	}
+	return ChannelPinned
+}
+
+// PlannedChart is a helm chart a planned step deploys.
+type PlannedChart struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	ReleaseName string `json:"releaseName,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	// Repository is the helm repository URL, or the OCI reference of the chart.
+	Repository string `json:"repository,omitempty"`
+}
+
+// PlanStep is the action planned for a single component.
+type PlanStep struct {
+	ID          string     `json:"id"`
+	Action      PlanAction `json:"action"`
+	HandlerType string     `json:"handlerType,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+
+	// Version is the resolved version the component ends up on, or is removed from.
+	Version          string `json:"version,omitempty"`
+	InstalledVersion string `json:"installedVersion,omitempty"`
+
+	Charts []PlannedChart `json:"charts,omitempty"`
+	// URLs are the manifests kubectl components apply.
+	URLs      []string `json:"urls,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+}
+
+// StackPlan is the ordered list of steps applying the Stack takes.
+type StackPlan struct {
+	// Hash identifies the steps of the plan.
+	Hash string `json:"hash"`
+	// SpecHash identifies the spec the plan was computed for, spec.mode left aside.
+	SpecHash      string      `json:"specHash"`
+	Steps         []PlanStep  `json:"steps,omitempty"`
+	GeneratedTime metav1.Time `json:"generatedTime,omitempty"`
+}
+
 // StackSpec defines the desired state of Stack.
 type StackSpec struct {
 	StackName string `json:"stackName"`
@@ -36,17 +225,127 @@ type StackSpec struct {
 	DisableComponents []string `json:"disableComponents,omitempty"`
 
 	Overrides *apiextensionsv1.JSON `json:"overrides,omitempty"`
+
+	// +kubebuilder:default=report
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// +kubebuilder:default=retry
+	// +optional
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+
+	// +kubebuilder:default=apply
+	// +optional
+	Mode StackMode `json:"mode,omitempty"`
+
+	// RequireApproval holds back changes to the cluster until the plan is approved
+	// through the app.ksctl.com/approved-plan annotation.
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+
+	// Updates upgrades the components along update channels.
+	// +optional
+	Updates *UpdatePolicy `json:"updates,omitempty"`
+}
+
+// FailureStatus describes how the last failed install attempt was handled.
+type FailureStatus struct {
+	Action  FailurePolicy `json:"action"`
+	Message string        `json:"message,omitempty"`
+	// ObservedGeneration of the Stack the attempt was made for, a paused or rolled
+	// back stack is not attempted again until its generation changes.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// RolledBack lists the components uninstalled by the rollback, in order.
+	RolledBack []string `json:"rolledBack,omitempty"`
+	// RollbackError is set when the rollback itself failed.
+	RollbackError string `json:"rollbackError,omitempty"`
+
+	Time metav1.Time `json:"time,omitempty"`
+}
+
+// ComponentStatus is the observed state of a single component of the stack.
+type ComponentStatus struct {
+	ID          string `json:"id"`
+	HandlerType string `json:"handlerType,omitempty"`
+
+	// VersionConstraint is the semver constraint of the version override, like
+	// "~1.22", the desired version is the release it resolved to.
+	VersionConstraint string `json:"versionConstraint,omitempty"`
+	DesiredVersion    string `json:"desiredVersion,omitempty"`
+	InstalledVersion  string `json:"installedVersion,omitempty"`
+	// PreviousVersion is the version the component ran before its last upgrade.
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	// AvailableVersion is the newest release on the update channel of the component,
+	// set until the component got upgraded to it.
+	AvailableVersion string `json:"availableVersion,omitempty"`
+
+	Phase              ComponentPhase `json:"phase,omitempty"`
+	LastError          string         `json:"lastError,omitempty"`
+	LastTransitionTime metav1.Time    `json:"lastTransitionTime,omitempty"`
+}
+
+// UpdateStatus describes the last check for updates of the components following a channel.
+type UpdateStatus struct {
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// LastError is set when the last check failed, the updates found before are kept.
+	LastError string `json:"lastError,omitempty"`
+	// NextWindowTime is when the maintenance window opens next, set while updates wait for it.
+	NextWindowTime *metav1.Time `json:"nextWindowTime,omitempty"`
 }
 
 // StackStatus defines the observed state of Stack.
 type StackStatus struct {
 	StatusCode      StackStatusCode `json:"statusCode,omitempty"`
 	ReasonOfFailure string          `json:"reasonOfFailure,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Components lists the components in the order the manager first acted on
+	// them, which follows the install order of the stack. Components of the same
+	// dependency level may come in any order.
+	// +optional
+	// +listType=map
+	// +listMapKey=id
+	Components []ComponentStatus `json:"components,omitempty"`
+
+	// +optional
+	LastFailure *FailureStatus `json:"lastFailure,omitempty"`
+
+	// RetryCount is the number of failed attempts in a row.
+	// +optional
+	RetryCount int32 `json:"retryCount,omitempty"`
+	// +optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+
+	// Plan is written in plan mode, it is dropped once applied.
+	// +optional
+	Plan *StackPlan `json:"plan,omitempty"`
+
+	// +optional
+	Updates *UpdateStatus `json:"updates,omitempty"`
+
+	// LastDriftCheckTime is when the installed components were last compared
+	// with the cluster, the next check waits for the drift check interval.
+	// +optional
+	LastDriftCheckTime *metav1.Time `json:"lastDriftCheckTime,omitempty"`
+
+	// State is the installation record of the stack, only written when the
+	// manager stores its state on the Stack status.
+	// +optional
+	State *apiextensionsv1.JSON `json:"state,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Stack",type=string,JSONPath=`.spec.stackName`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.statusCode`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Stack is the Schema for the stacks API.
 type Stack struct {
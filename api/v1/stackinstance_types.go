@@ -0,0 +1,79 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionAdmitted is True while a StackPolicy allows the StackInstance in its namespace.
+const ConditionAdmitted string = "Admitted"
+
+const (
+	// InstanceNamespaceLabel and InstanceNameLabel point the Stack installing a
+	// StackInstance back to it.
+	InstanceNamespaceLabel string = "app.ksctl.com/instance-namespace"
+	InstanceNameLabel      string = "app.ksctl.com/instance-name"
+)
+
+// StackInstanceSpec defines the desired state of StackInstance. The components
+// accepting a namespace override are installed into the namespace of the
+// StackInstance.
+type StackInstanceSpec struct {
+	StackSpec `json:",inline"`
+}
+
+// StackInstanceStatus defines the observed state of StackInstance, it mirrors
+// the status of the Stack the instance is installed as.
+type StackInstanceStatus struct {
+	StackStatus `json:",inline"`
+
+	// Stack is the cluster-scoped Stack the instance is installed as.
+	// +optional
+	Stack string `json:"stack,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Stack",type=string,JSONPath=`.spec.stackName`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.statusCode`
+// +kubebuilder:printcolumn:name="Admitted",type=string,JSONPath=`.status.conditions[?(@.type=="Admitted")].status`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// StackInstance is a stack requested by an application team for its namespace,
+// it is only installed as far as the StackPolicy objects allow.
+type StackInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StackInstanceSpec   `json:"spec,omitempty"`
+	Status StackInstanceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// StackInstanceList contains a list of StackInstance.
+type StackInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StackInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StackInstance{}, &StackInstanceList{})
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnyOverride allows every override key of a component.
+const AnyOverride string = "*"
+
+// AllowedStack is a stack the StackInstances of the namespaces may install.
+type AllowedStack struct {
+	StackName string `json:"stackName"`
+
+	// Components the instances may install, every component of the stack when
+	// empty. The others have to be listed in spec.disableComponents.
+	// +optional
+	Components []string `json:"components,omitempty"`
+
+	// Overrides lists per component the override keys the instances may set,
+	// "*" allows all of them. Components missing here accept none.
+	// +optional
+	Overrides map[string][]string `json:"overrides,omitempty"`
+
+	// EnforcedOverrides are applied over the overrides of the instances, keyed
+	// by component id like spec.overrides of a Stack.
+	// +optional
+	EnforcedOverrides *apiextensionsv1.JSON `json:"enforcedOverrides,omitempty"`
+
+	// RequireApproval holds back the changes of the instances until their plan
+	// is approved on the Stack, which the teams can't annotate themselves. The
+	// approved-plan annotation of the instances is ignored.
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+}
+
+// StackPolicySpec defines which stacks the StackInstances of the namespaces
+// may install.
+type StackPolicySpec struct {
+	// Namespaces the policy applies to.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects further namespaces the policy applies to.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// +optional
+	Stacks []AllowedStack `json:"stacks,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// StackPolicy lets the application teams install stacks in their namespaces
+// through StackInstances. A StackInstance is admitted when a single rule of
+// the policies applying to its namespace allows it.
+type StackPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StackPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// StackPolicyList contains a list of StackPolicy.
+type StackPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StackPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StackPolicy{}, &StackPolicyList{})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command bundle exports a stack for clusters without internet egress: the
+// charts, manifests, version catalog and the list of images, archived with an
+// index. The manager consumes it through --mirror.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/ksctl/ksctl/v2/pkg/poller"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ksctl/ka/internal/bundle"
+)
+
+func main() {
+	var opts bundle.Options
+	var overridesFile, disable, outDir string
+	flag.StringVar(&opts.StackName, "stack", "", "The stack to bundle, e.g. gitops-standard.")
+	flag.StringVar(&overridesFile, "overrides", "",
+		"A YAML or JSON file with the overrides of the components, keyed by component id as in a Stack.")
+	flag.StringVar(&disable, "disable", "", "Comma separated components left out of the bundle.")
+	flag.StringVar(&opts.Version, "version", "", "The version of the bundle, defaults to the time of the export.")
+	flag.StringVar(&outDir, "output", ".", "The directory the archive is written to.")
+	flag.Parse()
+
+	if err := run(opts, overridesFile, disable, outDir); err != nil {
+		fmt.Fprintln(os.Stderr, "bundle:", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts bundle.Options, overridesFile, disable, outDir string) error {
+	if len(opts.StackName) == 0 {
+		return fmt.Errorf("--stack is required")
+	}
+	if len(overridesFile) != 0 {
+		raw, err := os.ReadFile(overridesFile)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(raw, &opts.Overrides); err != nil {
+			return fmt.Errorf("overrides %s: %w", overridesFile, err)
+		}
+	}
+	if len(disable) != 0 {
+		opts.DisableComponents = strings.Split(disable, ",")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dir, err := os.MkdirTemp("", "ka-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	poller.InitSharedGithubReleasePoller()
+	index, err := bundle.Export(ctx, opts, dir)
+	if err != nil {
+		return err
+	}
+
+	out := filepath.Join(outDir, bundle.ArchiveName(index))
+	if err := bundle.Archive(dir, out); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s: %d components, %d images\n", out, len(index.Components), len(index.Images))
+	return nil
+}
@@ -17,10 +17,13 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/ksctl/ksctl/v2/pkg/poller"
 
@@ -29,11 +32,13 @@ import (
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -41,7 +46,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/apps"
+	"github.com/ksctl/ka/internal/bundle"
 	"github.com/ksctl/ka/internal/controller"
+	"github.com/ksctl/ka/internal/mirror"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ka/internal/tracing"
+	webhookappv1 "github.com/ksctl/ka/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -66,6 +77,12 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var stateStorage, stateNamespace string
+	var driftCheckInterval, readinessTimeout time.Duration
+	var maxRetries, maxConcurrentReconciles, maxParallelComponents int
+	var chartMirror, manifestMirror, versionCatalog string
+	var versionSource, componentVersionSources string
+	tracingOpts := tracing.OptionsFromEnv()
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -84,6 +101,40 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&stateStorage, "state-storage", string(controller.StorageConfigMap),
+		"Where the state of every Stack is stored, one of configmap, secret or status.")
+	flag.StringVar(&stateNamespace, "state-namespace", controller.DefaultStateNamespace,
+		"The namespace holding the state ConfigMaps or Secrets.")
+	flag.DurationVar(&driftCheckInterval, "drift-check-interval", 10*time.Minute,
+		"How often installed stacks are checked for drift, 0 disables the periodic checks.")
+	flag.DurationVar(&readinessTimeout, "component-ready-timeout", controller.DefaultReadinessTimeout,
+		"How long to wait for a component, or the helm upgrade of one, to become ready before deploying the next one.")
+	flag.IntVar(&maxRetries, "max-retries", int(controller.DefaultMaxRetries),
+		"How many failed attempts in a row a Stack gets before it stalls, 0 retries forever.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"How many Stacks are reconciled at once, Stacks sharing helm releases or namespaces still go one after the other.")
+	flag.IntVar(&maxParallelComponents, "max-parallel-components", controller.DefaultMaxParallelComponents,
+		"How many independent components of a Stack are installed at once.")
+	flag.StringVar(&tracingOpts.Endpoint, "otlp-endpoint", tracingOpts.Endpoint,
+		"The OTLP/gRPC collector the traces are exported to, tracing is disabled when empty. "+
+			"Defaults to OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT.")
+	flag.BoolVar(&tracingOpts.Insecure, "otlp-insecure", tracingOpts.Insecure,
+		"If set, the traces are exported without TLS.")
+	flag.Float64Var(&tracingOpts.SampleRatio, "trace-sample-ratio", tracingOpts.SampleRatio,
+		"The share of reconciles which get traced, between 0 and 1.")
+	flag.StringVar(&chartMirror, "mirror", "",
+		"Where the charts and manifests are fetched from instead of upstream: an OCI registry (oci://host/path), "+
+			"an HTTP server, a directory or a bundle archive. The catalog of a bundle is used unless --version-catalog is set.")
+	flag.StringVar(&manifestMirror, "manifest-mirror", "",
+		"Where the manifests are fetched from, an HTTP server or a directory. Defaults to --mirror unless it is an OCI registry.")
+	flag.StringVar(&versionCatalog, "version-catalog", "",
+		"A YAML file listing the releases of every upstream project, e.g. a mounted ConfigMap. "+
+			"It becomes the version source unless --version-source is set.")
+	flag.StringVar(&versionSource, "version-source", "",
+		"Where the versions of the components are listed from unless selected otherwise: github, helm, oci or catalog. "+
+			"Components which don't offer it keep their own order, starting with github.")
+	flag.StringVar(&componentVersionSources, "component-version-sources", "",
+		"The version source of single components, e.g. cert-manager=helm,spin-operator=oci.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -92,6 +143,12 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -199,20 +256,60 @@ func main() {
 		// if you are doing or is intended to do any operation such as perform cleanups
 		// after the manager stops then its usage might be unsafe.
 		// LeaderElectionReleaseOnCancel: true,
+
+		// The state is read right before being written, caching it would mean watching
+		// every ConfigMap and Secret in the cluster for a handful of objects.
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.ConfigMap{}, &corev1.Secret{}},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	storage, err := controller.NewStorage(controller.StorageKind(stateStorage), mgr.GetClient(), stateNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to create state storage")
+		os.Exit(1)
+	}
 	if err = (&controller.StackReconciler{
-		Client:     mgr.GetClient(),
-		Scheme:     mgr.GetScheme(),
-		RestConfig: mgr.GetConfig(),
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		RestConfig:              mgr.GetConfig(),
+		Storage:                 storage,
+		StateNamespace:          stateNamespace,
+		DriftCheckInterval:      driftCheckInterval,
+		ReadinessTimeout:        readinessTimeout,
+		MaxRetries:              int32(maxRetries),
+		Recorder:                mgr.GetEventRecorderFor("stack-controller"),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		MaxParallelComponents:   maxParallelComponents,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Stack")
 		os.Exit(1)
 	}
+	if err = (&controller.StackInstanceReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("stackinstance-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "StackInstance")
+		os.Exit(1)
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookappv1.SetupStackWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Stack")
+			os.Exit(1)
+		}
+		if err = webhookappv1.SetupStackInstanceWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "StackInstance")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -240,12 +337,71 @@ func main() {
 		os.Exit(1)
 	}
 
+	if len(chartMirror) != 0 {
+		sources := []*string{&chartMirror, &manifestMirror}
+		for _, src := range sources {
+			if len(*src) == 0 || strings.Contains(*src, "://") {
+				continue
+			}
+			dir, err := bundle.Open(*src)
+			if err != nil {
+				setupLog.Error(err, "unable to open the mirror", "path", *src)
+				os.Exit(1)
+			}
+			catalog := filepath.Join(dir, bundle.CatalogFile)
+			if _, err := os.Stat(catalog); err == nil && len(versionCatalog) == 0 {
+				versionCatalog = catalog
+			}
+			dirServer, err := mirror.NewDirServer(dir)
+			if err != nil {
+				setupLog.Error(err, "unable to serve the mirror directory", "dir", dir)
+				os.Exit(1)
+			}
+			if err := mgr.Add(dirServer); err != nil {
+				setupLog.Error(err, "unable to add the mirror directory server to manager")
+				os.Exit(1)
+			}
+			*src = dirServer.URL()
+		}
+		m, err := mirror.New(chartMirror, manifestMirror)
+		if err != nil {
+			setupLog.Error(err, "unable to set up the mirror")
+			os.Exit(1)
+		}
+		setupLog.Info("Fetching charts and manifests from the mirror", "charts", m.Charts, "manifests", m.Manifests)
+		stacks.UseMirror(m)
+	}
+
 	// Start the Ksctl Poller
 	poller.InitSharedGithubReleasePoller()
 
+	if len(versionCatalog) != 0 {
+		catalog, err := mirror.LoadCatalog(versionCatalog)
+		if err != nil {
+			setupLog.Error(err, "unable to load the version catalog")
+			os.Exit(1)
+		}
+		apps.UseCatalog(catalog)
+		if len(versionSource) == 0 {
+			versionSource = string(apps.VersionSourceCatalog)
+		}
+	}
+	selectedSources, err := apps.ParseVersionSources(componentVersionSources)
+	if err != nil {
+		setupLog.Error(err, "unable to parse the component version sources")
+		os.Exit(1)
+	}
+	if err := apps.UseVersionSources(apps.VersionSource(versionSource), selectedSources); err != nil {
+		setupLog.Error(err, "unable to select the version sources")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "problem flushing traces")
+	}
 }
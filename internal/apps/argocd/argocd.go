@@ -9,6 +9,13 @@ import (
 	"github.com/ksctl/ksctl/v2/pkg/utilities"
 )
 
+var OverridingsSchema = apps.OverridesSchema{
+	"version":          apps.OverrideExactVersion,
+	"noUI":             apps.OverrideBool,
+	"namespaceInstall": apps.OverrideBool,
+	"namespace":        apps.OverrideString,
+}
+
 func getArgocdComponentOverridings(p stack.ComponentOverrides) (
 	version *string,
 	noUI *bool,
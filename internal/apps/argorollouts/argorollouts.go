@@ -7,10 +7,19 @@ import (
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 	"github.com/ksctl/ksctl/v2/pkg/k8s"
 	"github.com/ksctl/ksctl/v2/pkg/utilities"
+)
 
-	"github.com/ksctl/ksctl/v2/pkg/poller"
+var versions = apps.NewVersions("argo-rollouts",
+	apps.GitHubReleases("argoproj", "argo-rollouts"),
+	apps.CatalogEntry("argoproj", "argo-rollouts"),
 )
 
+var OverridingsSchema = apps.OverridesSchema{
+	"version":          apps.OverrideString,
+	"namespaceInstall": apps.OverrideBool,
+	"namespace":        apps.OverrideString,
+}
+
 func getArgorolloutsComponentOverridings(p stack.ComponentOverrides) (
 	version *string,
 	namespaceInstall *bool,
@@ -46,11 +55,6 @@ func setArgorolloutsComponentOverridings(params stack.ComponentOverrides) (
 	namespace string,
 	err error,
 ) {
-	releases, err := poller.GetSharedPoller().Get("argoproj", "argo-rollouts")
-	if err != nil {
-		return "", nil, "", "", err
-	}
-
 	url = nil
 	postInstall = ""
 	namespace = "argo-rollouts"
@@ -63,7 +67,11 @@ func setArgorolloutsComponentOverridings(params stack.ComponentOverrides) (
 		}
 	}
 
-	version = apps.GetVersionIfItsNotNilAndLatest(_version, releases[0])
+	version, err = versions.Resolve(_version)
+	if err != nil {
+		return "", nil, "", "", err
+	}
+	version = apps.TagVersion(version)
 
 	generateManifestUrl := func(ver string, path string) string {
 		return fmt.Sprintf("https://raw.githubusercontent.com/argoproj/argo-rollouts/%s/%s", ver, path)
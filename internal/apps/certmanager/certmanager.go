@@ -2,17 +2,26 @@ package certmanager
 
 import (
 	"slices"
-	"strings"
 
 	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 	"github.com/ksctl/ksctl/v2/pkg/helm"
 
-	"github.com/ksctl/ksctl/v2/pkg/poller"
-
 	"github.com/ksctl/ksctl/v2/pkg/utilities"
 )
 
+var versions = apps.NewVersions("cert-manager",
+	apps.GitHubReleases("cert-manager", "cert-manager"),
+	apps.HelmIndex("https://charts.jetstack.io", "cert-manager"),
+	apps.CatalogEntry("cert-manager", "cert-manager"),
+)
+
+var OverridingsSchema = apps.OverridesSchema{
+	"version":                     apps.OverrideString,
+	"certmanagerChartOverridings": apps.OverrideObject,
+	"gatewayapiEnable":            apps.OverrideBool,
+}
+
 func getCertManagerComponentOverridings(p stack.ComponentOverrides) (
 	version *string,
 	gateway_apiEnable *bool,
@@ -48,11 +57,6 @@ func setCertManagerComponentOverridings(params stack.ComponentOverrides) (
 	err error,
 ) {
 
-	releases, err := poller.GetSharedPoller().Get("cert-manager", "cert-manager")
-	if err != nil {
-		return "", nil, err
-	}
-
 	overridings = map[string]any{
 		"crds": map[string]any{
 			"enabled": true,
@@ -61,7 +65,10 @@ func setCertManagerComponentOverridings(params stack.ComponentOverrides) (
 
 	_version, _gateway_apiEnable, _certmanagerChartOverridings := getCertManagerComponentOverridings(params)
 
-	version = apps.GetVersionIfItsNotNilAndLatest(_version, releases[0])
+	version, err = versions.Resolve(_version)
+	if err != nil {
+		return "", nil, err
+	}
 
 	if _certmanagerChartOverridings != nil {
 		utilities.CopySrcToDestPreservingDestVals(overridings, _certmanagerChartOverridings)
@@ -94,7 +101,7 @@ func CertManagerComponent(params stack.ComponentOverrides) (stack.Component, err
 		return stack.Component{}, err
 	}
 
-	version = strings.TrimPrefix(version, "v")
+	version = apps.ChartVersion(version)
 
 	return stack.Component{
 		HandlerType: stack.ComponentTypeHelm,
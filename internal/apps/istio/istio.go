@@ -1,16 +1,25 @@
 package istio
 
 import (
-	"strings"
-
 	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 	"github.com/ksctl/ksctl/v2/pkg/helm"
 
-	"github.com/ksctl/ksctl/v2/pkg/poller"
 	"github.com/ksctl/ksctl/v2/pkg/utilities"
 )
 
+var versions = apps.NewVersions("istio",
+	apps.GitHubReleases("istio", "istio"),
+	apps.HelmIndex("https://istio-release.storage.googleapis.com/charts", "base"),
+	apps.CatalogEntry("istio", "istio"),
+)
+
+var OverridingsSchema = apps.OverridesSchema{
+	"version":                    apps.OverrideString,
+	"helmBaseChartOverridings":   apps.OverrideObject,
+	"helmIstiodChartOverridings": apps.OverrideObject,
+}
+
 func getIstioComponentOverridings(p stack.ComponentOverrides) (version *string, helmBaseChartOverridings map[string]interface{}, helmIstiodChartOverridings map[string]interface{}) {
 	helmBaseChartOverridings = nil // By default, it is nil
 	helmIstiodChartOverridings = nil
@@ -44,15 +53,13 @@ func setIsitoComponentOverridings(p stack.ComponentOverrides) (
 	helmIstiodChartOverridings map[string]any,
 	err error,
 ) {
-	releases, err := poller.GetSharedPoller().Get("istio", "istio")
+	_version, _helmBaseChartOverridings, _helmIstiodChartOverridings := getIstioComponentOverridings(p)
+
+	version, err = versions.Resolve(_version)
 	if err != nil {
 		return "", nil, nil, err
 	}
 
-	_version, _helmBaseChartOverridings, _helmIstiodChartOverridings := getIstioComponentOverridings(p)
-
-	version = apps.GetVersionIfItsNotNilAndLatest(_version, releases[0])
-
 	if _helmBaseChartOverridings != nil {
 		helmBaseChartOverridings = _helmBaseChartOverridings
 	} else {
@@ -80,7 +87,7 @@ func IstioStandardComponent(params stack.ComponentOverrides) (stack.Component, e
 		return stack.Component{}, err
 	}
 
-	version = strings.TrimPrefix(version, "v")
+	version = apps.ChartVersion(version)
 
 	return stack.Component{
 		Helm: &helm.App{
@@ -119,3 +119,13 @@ func TestIsitoComponentOverridingsWithAllParams(t *testing.T) {
 	assert.Equal(t, map[string]any{"baseKey": "baseValue"}, helmBaseChartOverridings)
 	assert.Equal(t, map[string]any{"istiodKey": "istiodValue"}, helmIstiodChartOverridings)
 }
+
+func TestIstioStandardComponentWithVersionConstraint(t *testing.T) {
+	component, err := IstioStandardComponent(stack.ComponentOverrides{"version": "~1.22"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.22.4", component.Helm.Charts[0].Version)
+	assert.Equal(t, "1.22.4", component.Helm.Charts[1].Version)
+
+	_, err = IstioStandardComponent(stack.ComponentOverrides{"version": "~1.23"})
+	assert.Error(t, err)
+}
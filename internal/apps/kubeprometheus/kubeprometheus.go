@@ -1,8 +1,6 @@
 package kubeprometheus
 
 import (
-	"strings"
-
 	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 	"github.com/ksctl/ksctl/v2/pkg/helm"
@@ -10,6 +8,11 @@ import (
 	"github.com/ksctl/ksctl/v2/pkg/utilities"
 )
 
+var OverridingsSchema = apps.OverridesSchema{
+	"version":                      apps.OverrideExactVersion,
+	"helmKubePromChartOverridings": apps.OverrideObject,
+}
+
 func getKubePrometheusComponentOverridings(p stack.ComponentOverrides) (version *string, helmKubePromChartOverridings map[string]interface{}) {
 	helmKubePromChartOverridings = nil // By default it is nil
 
@@ -57,7 +60,7 @@ func KubePrometheusStandardComponent(params stack.ComponentOverrides) stack.Comp
 
 	version, helmKubePromChartOverridings := setKubePrometheusComponentOverridings(params)
 
-	version = strings.TrimPrefix(version, "v")
+	version = apps.ChartVersion(version)
 
 	return stack.Component{
 		Helm: &helm.App{
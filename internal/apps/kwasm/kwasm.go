@@ -1,8 +1,6 @@
 package kwasm
 
 import (
-	"strings"
-
 	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 	"github.com/ksctl/ksctl/v2/pkg/helm"
@@ -17,6 +15,15 @@ const (
 	RuntimeSKU                  stack.ComponentID = "kwasm-runtime-class"
 )
 
+var (
+	OperatorOverridingsSchema = apps.OverridesSchema{
+		"version":                   apps.OverrideExactVersion,
+		OperatorChartOverridingsKey: apps.OverrideObject,
+	}
+	// RuntimeOverridingsSchema is empty as the runtime class manifest takes no overrides.
+	RuntimeOverridingsSchema = apps.OverridesSchema{}
+)
+
 func getKwasmOperatorComponentOverridings(p stack.ComponentOverrides) (
 	version *string,
 	kwasmOperatorChartOverridings map[string]any,
@@ -72,7 +79,7 @@ func KwasmComponent(params stack.ComponentOverrides) (stack.Component, error) {
 func KwasmOperatorComponent(params stack.ComponentOverrides) (stack.Component, error) {
 	version, kwasmOperatorChartOverridings := setKwasmOperatorComponentOverridings(params)
 
-	version = strings.TrimPrefix(version, "v")
+	version = apps.ChartVersion(version)
 
 	return stack.Component{
 		Helm: &helm.App{
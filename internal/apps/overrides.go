@@ -0,0 +1,60 @@
+package apps
+
+import (
+	"errors"
+	"fmt"
+)
+
+type OverrideType string
+
+const (
+	OverrideString OverrideType = "string"
+	OverrideBool   OverrideType = "bool"
+	OverrideObject OverrideType = "object"
+	// OverrideExactVersion is the version of a component without a version
+	// source, which is used as is and so can't be a constraint.
+	OverrideExactVersion OverrideType = "exactVersion"
+)
+
+var (
+	ErrUnknownOverride     = errors.New("unknown override")
+	ErrInvalidOverrideType = errors.New("invalid override type")
+	ErrVersionConstraint   = errors.New("version constraints need a version source")
+)
+
+// OverridesSchema lists the override keys a component understands along with
+// the type the value is expected to have once decoded from JSON.
+type OverridesSchema map[string]OverrideType
+
+func (s OverridesSchema) Check(key string, val any) error {
+	expected, ok := s[key]
+	if !ok {
+		return ErrUnknownOverride
+	}
+
+	valid := false
+	switch expected {
+	case OverrideString:
+		_, valid = val.(string)
+	case OverrideExactVersion:
+		var ver string
+		if ver, valid = val.(string); valid && IsVersionConstraint(ver) {
+			return fmt.Errorf("%w, use an exact version: %s", ErrVersionConstraint, ver)
+		}
+	case OverrideBool:
+		_, valid = val.(bool)
+	case OverrideObject:
+		_, valid = val.(map[string]any)
+	}
+	if !valid {
+		return fmt.Errorf("%w: expected %s, got %T", ErrInvalidOverrideType, expected, val)
+	}
+	return nil
+}
+
+// ResolvesVersions tells whether the component lists its versions from a
+// version source, so its version can be a constraint or follow a channel.
+func (s OverridesSchema) ResolvesVersions() bool {
+	t, ok := s["version"]
+	return ok && t != OverrideExactVersion
+}
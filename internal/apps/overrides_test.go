@@ -0,0 +1,51 @@
+package apps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverridesSchemaCheck(t *testing.T) {
+	schema := OverridesSchema{
+		"version":   OverrideString,
+		"noUI":      OverrideBool,
+		"overrides": OverrideObject,
+		"exact":     OverrideExactVersion,
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		val     any
+		wantErr error
+	}{
+		{name: "valid string", key: "version", val: "v1.0.0"},
+		{name: "valid bool", key: "noUI", val: true},
+		{name: "valid object", key: "overrides", val: map[string]any{"a": 1}},
+		{name: "unknown key", key: "nameSpace", val: "argocd", wantErr: ErrUnknownOverride},
+		{name: "bool as string", key: "noUI", val: "true", wantErr: ErrInvalidOverrideType},
+		{name: "object as string", key: "overrides", val: "a=1", wantErr: ErrInvalidOverrideType},
+		{name: "exact version", key: "exact", val: "v2.13.0"},
+		{name: "exact branch", key: "exact", val: "stable"},
+		{name: "constraint as exact version", key: "exact", val: "~2.10", wantErr: ErrVersionConstraint},
+		{name: "exact version as bool", key: "exact", val: true, wantErr: ErrInvalidOverrideType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Check(tt.key, tt.val)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOverridesSchemaResolvesVersions(t *testing.T) {
+	assert.True(t, OverridesSchema{"version": OverrideString}.ResolvesVersions())
+	assert.False(t, OverridesSchema{"version": OverrideExactVersion}.ResolvesVersions())
+	assert.False(t, OverridesSchema{"noUI": OverrideBool}.ResolvesVersions())
+}
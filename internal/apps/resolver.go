@@ -0,0 +1,256 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ksctl/ksctl/v2/pkg/poller"
+	"helm.sh/helm/v3/pkg/repo"
+	"oras.land/oras-go/pkg/registry"
+	"oras.land/oras-go/pkg/registry/remote"
+	"oras.land/oras-go/pkg/registry/remote/auth"
+	"sigs.k8s.io/yaml"
+)
+
+type VersionSource string
+
+const (
+	// VersionSourceGitHub lists the GitHub releases of the project.
+	VersionSourceGitHub VersionSource = "github"
+	// VersionSourceHelm lists the versions of the chart in the index.yaml of
+	// its helm repository.
+	VersionSourceHelm VersionSource = "helm"
+	// VersionSourceOCI lists the tags of an OCI repository.
+	VersionSourceOCI VersionSource = "oci"
+	// VersionSourceCatalog looks the releases up in the static catalog, see
+	// UseCatalog.
+	VersionSourceCatalog VersionSource = "catalog"
+)
+
+var VersionSources = []VersionSource{
+	VersionSourceGitHub,
+	VersionSourceHelm,
+	VersionSourceOCI,
+	VersionSourceCatalog,
+}
+
+var (
+	ErrNoVersions = errors.New("no versions found")
+	ErrNoCatalog  = errors.New("no version catalog in use")
+)
+
+// VersionCacheTTL is how long the versions listed from a helm repository or an
+// OCI registry are reused before they are listed again.
+var VersionCacheTTL = 10 * time.Minute
+
+// versionTimeout bounds listing the versions from a helm repository or an OCI
+// registry, the components are resolved without a context.
+const versionTimeout = 30 * time.Second
+
+// VersionResolver lists the released versions of a component, newest first.
+type VersionResolver interface {
+	Source() VersionSource
+	Versions() ([]string, error)
+}
+
+// cachedResolver keeps the versions last listed by the resolver, they are
+// reused for the ttl and whenever listing them again fails.
+type cachedResolver struct {
+	VersionResolver
+	ttl time.Duration
+
+	mu       sync.Mutex
+	versions []string
+	listed   time.Time
+}
+
+func cached(r VersionResolver, ttl time.Duration) *cachedResolver {
+	return &cachedResolver{VersionResolver: r, ttl: ttl}
+}
+
+func (c *cachedResolver) Versions() ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.versions != nil && time.Since(c.listed) < c.ttl {
+		return slices.Clone(c.versions), nil
+	}
+
+	versions, err := c.VersionResolver.Versions()
+	if err == nil && len(versions) == 0 {
+		err = ErrNoVersions
+	}
+	if err != nil {
+		if c.versions != nil {
+			// last known good
+			return slices.Clone(c.versions), nil
+		}
+		return nil, fmt.Errorf("%s: %w", c.Source(), err)
+	}
+	c.versions, c.listed = versions, time.Now()
+	return slices.Clone(versions), nil
+}
+
+type githubReleases struct {
+	org, repo string
+}
+
+// GitHubReleases lists the releases through the shared poller of ksctl, which
+// caches them on its own.
+func GitHubReleases(org, repo string) VersionResolver {
+	return cached(githubReleases{org: org, repo: repo}, 0)
+}
+
+func (r githubReleases) Source() VersionSource { return VersionSourceGitHub }
+
+func (r githubReleases) Versions() ([]string, error) {
+	return poller.GetSharedPoller().Get(r.org, r.repo)
+}
+
+type helmIndex struct {
+	repoURL, chart string
+}
+
+// HelmIndex lists the versions of the chart in the helm repository.
+func HelmIndex(repoURL, chart string) VersionResolver {
+	return cached(helmIndex{repoURL: strings.TrimSuffix(repoURL, "/"), chart: chart}, VersionCacheTTL)
+}
+
+func (r helmIndex) Source() VersionSource { return VersionSourceHelm }
+
+func (r helmIndex) Versions() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.repoURL+"/index.yaml", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s/index.yaml: %s", r.repoURL, res.Status)
+	}
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var index repo.IndexFile
+	if err := yaml.Unmarshal(raw, &index); err != nil {
+		return nil, fmt.Errorf("%s/index.yaml: %w", r.repoURL, err)
+	}
+	index.SortEntries()
+
+	versions := make([]string, 0, len(index.Entries[r.chart]))
+	for _, c := range index.Entries[r.chart] {
+		if c.Metadata != nil {
+			versions = append(versions, c.Version)
+		}
+	}
+	return versions, nil
+}
+
+type ociTags struct {
+	ref       string
+	plainHTTP bool
+}
+
+// OCITags lists the tags of the OCI repository which are semantic versions,
+// e.g. of a chart pushed to "oci://ghcr.io/spinkube/charts/spin-operator".
+func OCITags(ref string) VersionResolver {
+	return cached(ociTags{ref: strings.TrimPrefix(ref, "oci://")}, VersionCacheTTL)
+}
+
+func (r ociTags) Source() VersionSource { return VersionSourceOCI }
+
+func (r ociTags) Versions() ([]string, error) {
+	ref, err := registry.ParseReference(r.ref)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	tags, err := registry.Tags(ctx, &remote.Repository{
+		Client:    auth.DefaultClient,
+		Reference: ref,
+		PlainHTTP: r.plainHTTP,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sortVersions(tags), nil
+}
+
+// sortVersions orders the tags which are semantic versions newest first and
+// drops the others, like "latest" or a commit sha.
+func sortVersions(tags []string) []string {
+	type tagged struct {
+		tag string
+		ver *semver.Version
+	}
+	versions := make([]tagged, 0, len(tags))
+	for _, tag := range tags {
+		// helm pushes the "+" of the build metadata as "_"
+		if v, err := semver.NewVersion(strings.ReplaceAll(tag, "_", "+")); err == nil {
+			versions = append(versions, tagged{tag: tag, ver: v})
+		}
+	}
+	slices.SortStableFunc(versions, func(a, b tagged) int {
+		return b.ver.Compare(a.ver)
+	})
+
+	sorted := make([]string, 0, len(versions))
+	for _, v := range versions {
+		sorted = append(sorted, v.tag)
+	}
+	return sorted
+}
+
+// sharedCatalog backs the catalog source, e.g. a catalog mounted from a
+// ConfigMap or shipped in a bundle.
+var sharedCatalog struct {
+	sync.RWMutex
+	poller.Poller
+}
+
+// UseCatalog makes the catalog the one the catalog source looks the releases
+// up in, nil drops it.
+func UseCatalog(c poller.Poller) {
+	sharedCatalog.Lock()
+	defer sharedCatalog.Unlock()
+	sharedCatalog.Poller = c
+}
+
+type catalogEntry struct {
+	org, repo string
+}
+
+// CatalogEntry looks the releases of the GitHub project up in the catalog.
+func CatalogEntry(org, repo string) VersionResolver {
+	return cached(catalogEntry{org: org, repo: repo}, 0)
+}
+
+func (r catalogEntry) Source() VersionSource { return VersionSourceCatalog }
+
+func (r catalogEntry) Versions() ([]string, error) {
+	sharedCatalog.RLock()
+	defer sharedCatalog.RUnlock()
+	if sharedCatalog.Poller == nil {
+		return nil, ErrNoCatalog
+	}
+	return sharedCatalog.Get(r.org, r.repo)
+}
@@ -0,0 +1,118 @@
+package apps
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ksctl/ka/internal/mirror"
+)
+
+type fakeResolver struct {
+	src      VersionSource
+	versions []string
+	err      error
+	calls    int
+}
+
+func (r *fakeResolver) Source() VersionSource { return r.src }
+
+func (r *fakeResolver) Versions() ([]string, error) {
+	r.calls++
+	return r.versions, r.err
+}
+
+func TestCachedResolver(t *testing.T) {
+	upstream := &fakeResolver{src: VersionSourceHelm, versions: []string{"1.1.0", "1.0.0"}}
+	r := cached(upstream, time.Hour)
+
+	versions, err := r.Versions()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.1.0", "1.0.0"}, versions)
+	_, _ = r.Versions()
+	assert.Equal(t, 1, upstream.calls, "versions are reused for the ttl")
+
+	r.ttl = 0
+	upstream.err = errors.New("rate limited")
+	versions, err = r.Versions()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.1.0", "1.0.0"}, versions, "the last known good versions are kept")
+	assert.Equal(t, 2, upstream.calls)
+
+	_, err = cached(&fakeResolver{src: VersionSourceHelm}, 0).Versions()
+	assert.ErrorIs(t, err, ErrNoVersions)
+	_, err = cached(&fakeResolver{src: VersionSourceOCI, err: errors.New("rate limited")}, 0).Versions()
+	assert.EqualError(t, err, "oci: rate limited")
+}
+
+func TestHelmIndex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/charts/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`apiVersion: v1
+entries:
+  cert-manager:
+  - name: cert-manager
+    version: v1.15.3
+    apiVersion: v2
+  - name: cert-manager
+    version: v1.16.2
+    apiVersion: v2
+  other:
+  - name: other
+    version: 9.9.9
+    apiVersion: v2
+`))
+	}))
+	defer srv.Close()
+
+	versions, err := HelmIndex(srv.URL+"/charts/", "cert-manager").Versions()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1.16.2", "v1.15.3"}, versions)
+
+	_, err = HelmIndex(srv.URL, "cert-manager").Versions()
+	assert.ErrorContains(t, err, "404 Not Found")
+}
+
+func TestOCITags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/spinkube/charts/spin-operator/tags/list" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"spinkube/charts/spin-operator","tags":["0.2.0","latest","0.4.0","0.3.0_build.1"]}`))
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	versions, err := ociTags{ref: host + "/spinkube/charts/spin-operator", plainHTTP: true}.Versions()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0.4.0", "0.3.0_build.1", "0.2.0"}, versions)
+}
+
+func TestSortVersions(t *testing.T) {
+	assert.Equal(t,
+		[]string{"v1.10.0", "v1.9.1", "1.9.0", "v1.9.0-rc.1"},
+		sortVersions([]string{"1.9.0", "sha-3f2a1c", "v1.9.0-rc.1", "v1.10.0", "latest", "v1.9.1"}))
+}
+
+func TestCatalogEntry(t *testing.T) {
+	r := CatalogEntry("istio", "istio")
+	_, err := r.Versions()
+	assert.ErrorIs(t, err, ErrNoCatalog)
+
+	UseCatalog(mirror.Catalog{"istio/istio": {"1.22.4", "1.21.0"}})
+	defer UseCatalog(nil)
+	versions, err := r.Versions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.22.4", "1.21.0"}, versions)
+}
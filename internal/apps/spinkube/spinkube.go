@@ -2,7 +2,7 @@ package spinkube
 
 import (
 	"fmt"
-	"strings"
+	"maps"
 
 	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ka/internal/apps/kwasm"
@@ -10,11 +10,28 @@ import (
 	"github.com/ksctl/ksctl/v2/pkg/helm"
 	"github.com/ksctl/ksctl/v2/pkg/k8s"
 
-	"github.com/ksctl/ksctl/v2/pkg/poller"
-
 	"github.com/ksctl/ksctl/v2/pkg/utilities"
 )
 
+var (
+	// operatorVersions are shared by the manifests and the chart of the
+	// operator, which are released together.
+	operatorVersions = apps.NewVersions("spin-operator",
+		apps.GitHubReleases("spinkube", "spin-operator"),
+		apps.OCITags("oci://ghcr.io/spinkube/charts/spin-operator"),
+		apps.CatalogEntry("spinkube", "spin-operator"),
+	)
+	shimVersions = apps.NewVersions("containerd-shim-spin",
+		apps.GitHubReleases("spinkube", "containerd-shim-spin"),
+		apps.OCITags("ghcr.io/spinkube/containerd-shim-spin/node-installer"),
+		apps.CatalogEntry("spinkube", "containerd-shim-spin"),
+	)
+)
+
+var OverridingsSchema = apps.OverridesSchema{
+	"version": apps.OverrideString,
+}
+
 func getSpinkubeComponentOverridings(p stack.ComponentOverrides) (version *string) {
 	if p == nil {
 		return nil
@@ -31,29 +48,56 @@ func getSpinkubeComponentOverridings(p stack.ComponentOverrides) (version *strin
 	return
 }
 
-func GetSpinKubeStackSpecificKwasmOverrides(params stack.ComponentOverrides) error {
-	releases, err := poller.GetSharedPoller().Get("spinkube", "containerd-shim-spin")
-	if err != nil {
-		return err
+// ShimVersionKey is the override of the kwasm operator holding the version of
+// the containerd shim its node installer puts on the nodes.
+const ShimVersionKey = "shimVersion"
+
+// KwasmOperatorOverridingsSchema is what the kwasm operator accepts as part of
+// the spinkube stack.
+var KwasmOperatorOverridingsSchema = apps.OverridesSchema{
+	"version":                         apps.OverrideExactVersion,
+	kwasm.OperatorChartOverridingsKey: apps.OverrideObject,
+	ShimVersionKey:                    apps.OverrideString,
+}
+
+// ShimVersion resolves the version of the containerd shim the kwasm operator
+// is given.
+func ShimVersion(params stack.ComponentOverrides) (string, error) {
+	var ver *string
+	if v, ok := params[ShimVersionKey].(string); ok {
+		ver = utilities.Ptr(v)
 	}
-	nodeInstallerOCI := "ghcr.io/spinkube/containerd-shim-spin/node-installer:" + releases[0]
+	return shimVersions.Resolve(ver)
+}
 
-	if params == nil {
-		params = stack.ComponentOverrides{}
+// GetSpinKubeStackSpecificKwasmOverrides returns the overrides of the kwasm
+// operator with its node installer set to the one of the spin shim. The
+// overrides it is given are left untouched.
+func GetSpinKubeStackSpecificKwasmOverrides(params stack.ComponentOverrides) (stack.ComponentOverrides, error) {
+	shimVersion, err := ShimVersion(params)
+	if err != nil {
+		return nil, err
 	}
-	if _, ok := params[kwasm.OperatorChartOverridingsKey]; !ok {
-		params[kwasm.OperatorChartOverridingsKey] = map[string]any{}
+	nodeInstallerOCI := "ghcr.io/spinkube/containerd-shim-spin/node-installer:" + apps.TagVersion(shimVersion)
+
+	overrides := maps.Clone(params)
+	if overrides == nil {
+		overrides = stack.ComponentOverrides{}
 	}
 
-	if _, ok := params[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"]; !ok {
-		params[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"] = map[string]any{
-			"installerImage": nodeInstallerOCI,
-		}
-	} else {
-		params[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"].(map[string]any)["installerImage"] = nodeInstallerOCI
+	chartOverridings := map[string]any{}
+	if v, ok := overrides[kwasm.OperatorChartOverridingsKey].(map[string]any); ok {
+		chartOverridings = utilities.DeepCopyMap(v)
 	}
+	kwasmOperator, ok := chartOverridings["kwasmOperator"].(map[string]any)
+	if !ok {
+		kwasmOperator = map[string]any{}
+	}
+	kwasmOperator["installerImage"] = nodeInstallerOCI
+	chartOverridings["kwasmOperator"] = kwasmOperator
+	overrides[kwasm.OperatorChartOverridingsKey] = chartOverridings
 
-	return nil
+	return overrides, nil
 }
 
 func setSpinkubeComponentOverridings(p stack.ComponentOverrides, theThing string) (
@@ -62,15 +106,15 @@ func setSpinkubeComponentOverridings(p stack.ComponentOverrides, theThing string
 	postInstall string,
 	err error,
 ) {
-	releases, err := poller.GetSharedPoller().Get("spinkube", "spin-operator")
-	if err != nil {
-		return
-	}
 	url = ""
 	postInstall = ""
 
 	_version := getSpinkubeComponentOverridings(p)
-	version = apps.GetVersionIfItsNotNilAndLatest(_version, releases[0])
+	version, err = operatorVersions.Resolve(_version)
+	if err != nil {
+		return
+	}
+	version = apps.TagVersion(version)
 
 	defaultVals := func() {
 		url = fmt.Sprintf("https://github.com/spinkube/spin-operator/releases/download/%s/%s", version, theThing)
@@ -133,9 +177,12 @@ const (
 
 func SpinOperatorComponent(params stack.ComponentOverrides) (stack.Component, error) {
 
-	version, helmOverride := setSpinOperatorComponentOverridings(params)
+	version, helmOverride, err := setSpinOperatorComponentOverridings(params)
+	if err != nil {
+		return stack.Component{}, err
+	}
 
-	version = strings.TrimPrefix(version, "v")
+	version = apps.ChartVersion(version)
 
 	return stack.Component{
 		HandlerType: stack.ComponentTypeHelm,
@@ -155,6 +202,11 @@ func SpinOperatorComponent(params stack.ComponentOverrides) (stack.Component, er
 	}, nil
 }
 
+var OperatorOverridingsSchema = apps.OverridesSchema{
+	"version":                      apps.OverrideString,
+	"helmOperatorChartOverridings": apps.OverrideObject,
+}
+
 func getSpinkubeOperatorComponentOverridings(p stack.ComponentOverrides) (version *string, helmOperatorChartOverridings map[string]interface{}) {
 	helmOperatorChartOverridings = nil // By default, it is nil
 
@@ -180,18 +232,17 @@ func getSpinkubeOperatorComponentOverridings(p stack.ComponentOverrides) (versio
 func setSpinOperatorComponentOverridings(p stack.ComponentOverrides) (
 	version string,
 	helmOperatorChartOverridings map[string]any,
+	err error,
 ) {
 
-	releases, err := poller.GetSharedPoller().Get("spinkube", "spin-operator")
-	if err != nil {
-		return
-	}
-
 	helmOperatorChartOverridings = map[string]any{}
 
 	_version, _helmOperatorChartOverridings := getSpinkubeOperatorComponentOverridings(p)
 
-	version = apps.GetVersionIfItsNotNilAndLatest(_version, releases[0])
+	version, err = operatorVersions.Resolve(_version)
+	if err != nil {
+		return
+	}
 
 	if _helmOperatorChartOverridings != nil {
 		helmOperatorChartOverridings = _helmOperatorChartOverridings
@@ -1,9 +1,11 @@
 package spinkube
 
 import (
+	"errors"
 	"sort"
 	"testing"
 
+	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ka/internal/apps/kwasm"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 	"github.com/ksctl/ksctl/v2/pkg/poller"
@@ -33,12 +35,13 @@ func TestMain(m *testing.M) {
 
 func TestGetSpinKubeStackSpecificKwasmOverrides_DefaultValues(t *testing.T) {
 	params := stack.ComponentOverrides{}
-	err := GetSpinKubeStackSpecificKwasmOverrides(params)
+	overrides, err := GetSpinKubeStackSpecificKwasmOverrides(params)
 
 	assert.NoError(t, err)
-	assert.NotNil(t, params[kwasm.OperatorChartOverridingsKey])
-	assert.NotNil(t, params[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"])
-	assert.Equal(t, "ghcr.io/spinkube/containerd-shim-spin/node-installer:v0.15.1", params[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"].(map[string]any)["installerImage"])
+	assert.NotNil(t, overrides[kwasm.OperatorChartOverridingsKey])
+	assert.NotNil(t, overrides[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"])
+	assert.Equal(t, "ghcr.io/spinkube/containerd-shim-spin/node-installer:v0.15.1", overrides[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"].(map[string]any)["installerImage"])
+	assert.Empty(t, params)
 }
 
 func TestGetSpinKubeStackSpecificKwasmOverrides_WithExistingOverrides(t *testing.T) {
@@ -49,18 +52,35 @@ func TestGetSpinKubeStackSpecificKwasmOverrides_WithExistingOverrides(t *testing
 			},
 		},
 	}
-	err := GetSpinKubeStackSpecificKwasmOverrides(params)
+	overrides, err := GetSpinKubeStackSpecificKwasmOverrides(params)
 
 	assert.NoError(t, err)
 	assert.Equal(t,
 		"ghcr.io/spinkube/containerd-shim-spin/node-installer:v0.15.1",
+		overrides[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"].(map[string]any)["installerImage"])
+	assert.Equal(t,
+		"existing-image",
 		params[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"].(map[string]any)["installerImage"])
 }
 
 func TestGetSpinKubeStackSpecificKwasmOverrides_NilParams(t *testing.T) {
-	err := GetSpinKubeStackSpecificKwasmOverrides(nil)
+	nilParams, err := GetSpinKubeStackSpecificKwasmOverrides(nil)
+	assert.NoError(t, err)
 
+	emptyParams, err := GetSpinKubeStackSpecificKwasmOverrides(stack.ComponentOverrides{})
 	assert.NoError(t, err)
+	assert.Equal(t, emptyParams, nilParams)
+}
+
+func TestGetSpinKubeStackSpecificKwasmOverrides_PinnedShimVersion(t *testing.T) {
+	overrides, err := GetSpinKubeStackSpecificKwasmOverrides(stack.ComponentOverrides{
+		ShimVersionKey: "v0.0.1",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"ghcr.io/spinkube/containerd-shim-spin/node-installer:v0.0.1",
+		overrides[kwasm.OperatorChartOverridingsKey].(map[string]any)["kwasmOperator"].(map[string]any)["installerImage"])
 }
 
 func TestSpinkubeComponentOverridings_DefaultValues(t *testing.T) {
@@ -87,8 +107,9 @@ func TestSpinkubeComponentOverridings_WithOverrides(t *testing.T) {
 
 func TestSpinOperatorComponentOverridings_DefaultValues(t *testing.T) {
 	params := stack.ComponentOverrides{}
-	version, helmOverride := setSpinOperatorComponentOverridings(params)
+	version, helmOverride, err := setSpinOperatorComponentOverridings(params)
 
+	assert.NoError(t, err)
 	assert.Equal(t, "v0.2.0", version)
 	assert.NotNil(t, helmOverride)
 }
@@ -101,8 +122,9 @@ func TestSpinOperatorComponentOverridings_WithOverrides(t *testing.T) {
 				"someKey": "someValue",
 			},
 		}
-		version, helmOverride := setSpinOperatorComponentOverridings(params)
+		version, helmOverride, err := setSpinOperatorComponentOverridings(params)
 
+		assert.NoError(t, err)
 		assert.Equal(t, "v1.2.3", version)
 		assert.NotNil(t, helmOverride)
 		assert.Equal(t, "someValue", helmOverride["someKey"])
@@ -114,10 +136,40 @@ func TestSpinOperatorComponentOverridings_WithOverrides(t *testing.T) {
 				"someKey": "someValue",
 			},
 		}
-		version, helmOverride := setSpinOperatorComponentOverridings(params)
+		version, helmOverride, err := setSpinOperatorComponentOverridings(params)
 
+		assert.NoError(t, err)
 		assert.Equal(t, "1.2.3", version)
 		assert.NotNil(t, helmOverride)
 		assert.Equal(t, "someValue", helmOverride["someKey"])
 	})
 }
+
+func TestSpinOperatorComponent_UnresolvedVersion(t *testing.T) {
+	params := stack.ComponentOverrides{
+		"version": "~9.9",
+	}
+	_, _, err := setSpinOperatorComponentOverridings(params)
+	assert.ErrorIs(t, err, apps.ErrNoMatchingVersion)
+
+	_, err = SpinOperatorComponent(params)
+	assert.ErrorIs(t, err, apps.ErrNoMatchingVersion)
+}
+
+type failingSource struct{}
+
+func (failingSource) Source() apps.VersionSource { return apps.VersionSourceGitHub }
+
+func (failingSource) Versions() ([]string, error) { return nil, errors.New("rate limited") }
+
+func TestSpinOperatorComponent_FailingSource(t *testing.T) {
+	upstream := operatorVersions
+	operatorVersions = apps.NewVersions("spin-operator-outage", failingSource{})
+	defer func() { operatorVersions = upstream }()
+
+	_, err := SpinOperatorComponent(stack.ComponentOverrides{})
+	assert.ErrorContains(t, err, "rate limited")
+
+	_, err = SpinkubeOperatorCrdComponent(stack.ComponentOverrides{})
+	assert.ErrorContains(t, err, "rate limited")
+}
@@ -1,5 +1,22 @@
 package apps
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+var (
+	ErrNoVersionSource      = errors.New("no such version source")
+	ErrUnknownVersionSource = errors.New("unknown version source")
+	ErrNoMatchingVersion    = errors.New("no release satisfies the version constraint")
+)
+
 func GetVersionIfItsNotNilAndLatest(ver *string, defaultVer string) string {
 	if ver == nil {
 		return defaultVer
@@ -9,3 +26,207 @@ func GetVersionIfItsNotNilAndLatest(ver *string, defaultVer string) string {
 	}
 	return *ver
 }
+
+// VersionPin is a version a stack renders besides the ones of its components,
+// like the image one of its charts installs, read from the Key override of the
+// Component. It gets pinned on the Stack along with the versions of the
+// components, so it does not follow every upstream release.
+type VersionPin struct {
+	Component stack.ComponentID
+	Key       string
+	Resolve   func(params stack.ComponentOverrides) (string, error)
+}
+
+// Versions of a component, listed by whichever of its sources is selected
+// for it.
+type Versions struct {
+	name      string
+	resolvers []VersionResolver
+}
+
+// versionsByName holds the versions of every component, so a selection of
+// sources can be checked against what the components offer.
+var versionsByName = map[string]*Versions{}
+
+// NewVersions registers the sources the versions of the component can be
+// listed from, the first one is tried first when none is selected.
+func NewVersions(name string, resolvers ...VersionResolver) *Versions {
+	v := &Versions{name: name, resolvers: resolvers}
+	versionsByName[name] = v
+	return v
+}
+
+var versionSources struct {
+	sync.RWMutex
+	fallback VersionSource
+	selected map[string]VersionSource
+}
+
+// UseVersionSources selects where the versions of the components are listed
+// from, by component name. Components left out use the fallback source when
+// they offer it, and their own order of sources otherwise.
+func UseVersionSources(fallback VersionSource, selected map[string]VersionSource) error {
+	if len(fallback) != 0 && !slices.Contains(VersionSources, fallback) {
+		return fmt.Errorf("%w: %s", ErrUnknownVersionSource, fallback)
+	}
+	for name, src := range selected {
+		v, ok := versionsByName[name]
+		if !ok {
+			return fmt.Errorf("no component %s lists versions", name)
+		}
+		if v.resolver(src) == nil {
+			return fmt.Errorf("%w: %s for %s", ErrNoVersionSource, src, name)
+		}
+	}
+
+	versionSources.Lock()
+	defer versionSources.Unlock()
+	versionSources.fallback = fallback
+	versionSources.selected = selected
+	return nil
+}
+
+// ParseVersionSources reads a selection of sources like
+// "cert-manager=helm,spin-operator=oci".
+func ParseVersionSources(s string) (map[string]VersionSource, error) {
+	selected := map[string]VersionSource{}
+	for pair := range strings.SplitSeq(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+		name, src, ok := strings.Cut(pair, "=")
+		if !ok || len(name) == 0 {
+			return nil, fmt.Errorf("invalid version source %q, expected <component>=<source>", pair)
+		}
+		if !slices.Contains(VersionSources, VersionSource(src)) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownVersionSource, src)
+		}
+		selected[name] = VersionSource(src)
+	}
+	return selected, nil
+}
+
+func (v *Versions) resolver(src VersionSource) VersionResolver {
+	for _, r := range v.resolvers {
+		if r.Source() == src {
+			return r
+		}
+	}
+	return nil
+}
+
+// ordered puts the source selected for the component first, followed by the
+// other sources it offers.
+func (v *Versions) ordered() []VersionResolver {
+	versionSources.RLock()
+	src, ok := versionSources.selected[v.name]
+	if !ok {
+		src = versionSources.fallback
+	}
+	versionSources.RUnlock()
+
+	ordered := slices.Clone(v.resolvers)
+	if r := v.resolver(src); r != nil {
+		ordered = slices.DeleteFunc(ordered, func(o VersionResolver) bool { return o == r })
+		ordered = slices.Insert(ordered, 0, r)
+	}
+	return ordered
+}
+
+// List returns the versions of the component, newest first. Each source keeps
+// the versions it listed last, and when the selected one fails without them,
+// the other sources are tried in turn.
+func (v *Versions) List() ([]string, error) {
+	var errs []error
+	for _, r := range v.ordered() {
+		versions, err := r.Versions()
+		if err == nil {
+			return versions, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("versions of %s: %w", v.name, errors.Join(errs...))
+}
+
+// Latest returns the newest version of the component which is not a
+// pre-release.
+func (v *Versions) Latest() (string, error) {
+	versions, err := v.List()
+	if err != nil {
+		return "", err
+	}
+	for _, ver := range versions {
+		if sv, err := semver.NewVersion(ver); err != nil || len(sv.Prerelease()) == 0 {
+			return ver, nil
+		}
+	}
+	return "", fmt.Errorf("versions of %s: %w", v.name, ErrNoVersions)
+}
+
+// Resolve returns the version asked for. When it is unset or "latest" that is
+// the newest release, and for a constraint like "~1.22", ">=1.14 <1.16" or
+// "1.x" the newest release satisfying it. The sources are only asked in these
+// cases, and pre-releases only match constraints which name one themselves.
+func (v *Versions) Resolve(ver *string) (string, error) {
+	if ver == nil || *ver == "latest" {
+		return v.Latest()
+	}
+	if !IsVersionConstraint(*ver) {
+		return *ver, nil
+	}
+
+	constraint, _ := semver.NewConstraint(*ver)
+	versions, err := v.List()
+	if err != nil {
+		return "", err
+	}
+	for _, release := range versions {
+		if sv, err := semver.NewVersion(release); err == nil && constraint.Check(sv) {
+			return release, nil
+		}
+	}
+	return "", fmt.Errorf("versions of %s: %w: %s", v.name, ErrNoMatchingVersion, *ver)
+}
+
+// IsVersionConstraint tells whether the version override is a semver
+// constraint to resolve rather than a version.
+func IsVersionConstraint(ver string) bool {
+	if len(ver) == 0 || ver == "latest" {
+		return false
+	}
+	if _, err := semver.NewVersion(ver); err == nil {
+		return false
+	}
+	_, err := semver.NewConstraint(ver)
+	return err == nil
+}
+
+// SatisfiesVersion tells whether the version satisfies the constraint.
+func SatisfiesVersion(constraint, ver string) bool {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	sv, err := semver.NewVersion(ver)
+	return err == nil && c.Check(sv)
+}
+
+// ChartVersion is the version the way helm charts are versioned, without the
+// "v" prefix of the release tags.
+func ChartVersion(ver string) string {
+	return strings.TrimPrefix(ver, "v")
+}
+
+// TagVersion is the version the way it is tagged on GitHub, with the "v"
+// prefix, e.g. to build the URL of a release asset. Anything which is not a
+// version is kept as is.
+func TagVersion(ver string) string {
+	if strings.HasPrefix(ver, "v") {
+		return ver
+	}
+	if _, err := semver.StrictNewVersion(ver); err != nil {
+		return ver
+	}
+	return "v" + ver
+}
@@ -1,9 +1,11 @@
 package apps
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/ksctl/ksctl/v2/pkg/utilities"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGetVersionIfItsNotNilAndLatest(t *testing.T) {
@@ -42,3 +44,125 @@ func TestGetVersionIfItsNotNilAndLatest(t *testing.T) {
 		})
 	}
 }
+
+func TestVersions(t *testing.T) {
+	github := &fakeResolver{src: VersionSourceGitHub, versions: []string{"v1.15.3"}}
+	helm := &fakeResolver{src: VersionSourceHelm, versions: []string{"v1.16.2"}}
+	v := NewVersions("test-component", github, helm)
+	defer delete(versionsByName, "test-component")
+	defer func() { assert.NoError(t, UseVersionSources("", nil)) }()
+
+	latest, err := v.Latest()
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.15.3", latest, "the first source is tried first")
+
+	assert.NoError(t, UseVersionSources(VersionSourceHelm, nil))
+	latest, err = v.Latest()
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.16.2", latest)
+
+	assert.NoError(t, UseVersionSources(VersionSourceOCI, map[string]VersionSource{"test-component": VersionSourceGitHub}))
+	latest, err = v.Latest()
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.15.3", latest)
+
+	github.err = errors.New("rate limited")
+	latest, err = v.Latest()
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.16.2", latest, "the other sources are tried when the selected one fails")
+
+	helm.err = errors.New("not found")
+	_, err = v.Latest()
+	assert.EqualError(t, err, "versions of test-component: rate limited\nnot found")
+
+	calls := github.calls + helm.calls
+	ver, err := v.Resolve(utilities.Ptr("v1.0.0"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.0.0", ver)
+	assert.Equal(t, calls, github.calls+helm.calls, "a pinned version is not resolved")
+}
+
+func TestUseVersionSources(t *testing.T) {
+	NewVersions("test-component", &fakeResolver{src: VersionSourceGitHub})
+	defer delete(versionsByName, "test-component")
+	defer func() { assert.NoError(t, UseVersionSources("", nil)) }()
+
+	assert.ErrorIs(t, UseVersionSources("git", nil), ErrUnknownVersionSource)
+	assert.ErrorIs(t, UseVersionSources("", map[string]VersionSource{"test-component": VersionSourceOCI}), ErrNoVersionSource)
+	assert.Error(t, UseVersionSources("", map[string]VersionSource{"unknown": VersionSourceGitHub}))
+	assert.NoError(t, UseVersionSources(VersionSourceCatalog, map[string]VersionSource{"test-component": VersionSourceGitHub}))
+}
+
+func TestParseVersionSources(t *testing.T) {
+	selected, err := ParseVersionSources("cert-manager=helm, spin-operator=oci,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]VersionSource{"cert-manager": VersionSourceHelm, "spin-operator": VersionSourceOCI}, selected)
+
+	selected, err = ParseVersionSources("")
+	assert.NoError(t, err)
+	assert.Empty(t, selected)
+
+	_, err = ParseVersionSources("cert-manager")
+	assert.Error(t, err)
+	_, err = ParseVersionSources("cert-manager=git")
+	assert.ErrorIs(t, err, ErrUnknownVersionSource)
+}
+
+func TestVersionsResolveConstraint(t *testing.T) {
+	v := NewVersions("test-component", &fakeResolver{
+		src:      VersionSourceGitHub,
+		versions: []string{"v1.23.0-rc.1", "v1.22.4", "v1.22.3", "v1.21.0", "v1.15.2", "v1.14.7", "v0.9.0"},
+	})
+	defer delete(versionsByName, "test-component")
+
+	tests := []struct {
+		version string
+		want    string
+		wantErr error
+	}{
+		{version: "latest", want: "v1.22.4"},
+		{version: "~1.22", want: "v1.22.4"},
+		{version: ">=1.14 <1.16", want: "v1.15.2"},
+		{version: "1.x", want: "v1.22.4"},
+		{version: "0.x", want: "v0.9.0"},
+		{version: ">=1.23.0-rc.0", want: "v1.23.0-rc.1"},
+		{version: "v1.14.7", want: "v1.14.7"},
+		{version: "~2.0", wantErr: ErrNoMatchingVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := v.Resolve(utilities.Ptr(tt.version))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := NewVersions("test-component", &fakeResolver{
+		src:      VersionSourceGitHub,
+		versions: []string{"v2.0.0-beta.1"},
+	}).Latest()
+	assert.ErrorIs(t, err, ErrNoVersions)
+}
+
+func TestVersionFormats(t *testing.T) {
+	assert.True(t, IsVersionConstraint("~1.22"))
+	assert.True(t, IsVersionConstraint(">=1.14 <1.16"))
+	assert.True(t, IsVersionConstraint("1.x"))
+	assert.False(t, IsVersionConstraint("v1.22.4"))
+	assert.False(t, IsVersionConstraint("latest"))
+	assert.False(t, IsVersionConstraint(""))
+
+	assert.True(t, SatisfiesVersion("~1.22", "v1.22.4"))
+	assert.False(t, SatisfiesVersion("~1.22", "1.23.0"))
+	assert.False(t, SatisfiesVersion("~1.22", "stable"))
+
+	assert.Equal(t, "1.22.4", ChartVersion("v1.22.4"))
+	assert.Equal(t, "1.22.4", ChartVersion("1.22.4"))
+	assert.Equal(t, "v1.22.4", TagVersion("1.22.4"))
+	assert.Equal(t, "v1.22.4", TagVersion("v1.22.4"))
+	assert.Equal(t, "latest", TagVersion("latest"))
+}
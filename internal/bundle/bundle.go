@@ -0,0 +1,265 @@
+package bundle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+	"github.com/ksctl/ksctl/v2/pkg/poller"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ksctl/ka/internal/mirror"
+	"github.com/ksctl/ka/internal/stacks"
+)
+
+// APIVersion is the layout of the bundles written by this version of ka.
+const APIVersion string = "bundle.ka.ksctl.com/v1"
+
+const (
+	// IndexFile lists what the bundle holds.
+	IndexFile string = "bundle.yaml"
+	// CatalogFile holds the releases the versions got resolved from, see mirror.Catalog.
+	CatalogFile string = "catalog.yaml"
+	// ImagesFile lists the container images of the bundle one per line, for
+	// mirroring them into the local registry.
+	ImagesFile string = "images.txt"
+)
+
+// Index is the manifest of a bundle. Next to it the bundle holds the charts
+// and manifests in the layout of an HTTP mirror (see mirror.ChartsDir and
+// mirror.ManifestsDir).
+type Index struct {
+	APIVersion string    `json:"apiVersion"`
+	Version    string    `json:"version"`
+	Created    time.Time `json:"created"`
+
+	StackName         string                    `json:"stackName"`
+	DisableComponents []string                  `json:"disableComponents,omitempty"`
+	Overrides         map[string]map[string]any `json:"overrides,omitempty"`
+
+	Components []Component `json:"components"`
+	// Images is every image of the components, sorted.
+	Images []string `json:"images"`
+}
+
+type Component struct {
+	ID          string     `json:"id"`
+	HandlerType string     `json:"handlerType"`
+	Version     string     `json:"version"`
+	Charts      []Chart    `json:"charts,omitempty"`
+	Manifests   []Manifest `json:"manifests,omitempty"`
+	Images      []string   `json:"images,omitempty"`
+}
+
+type Chart struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Source is the helm repository or the OCI reference the chart came from.
+	Source string `json:"source"`
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+type Manifest struct {
+	URL    string `json:"url"`
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+// Options tells what goes into the bundle.
+type Options struct {
+	StackName         string
+	Overrides         map[string]map[string]any
+	DisableComponents []string
+	// Version of the bundle, defaults to the time of the export.
+	Version string
+}
+
+// Export resolves the stack and writes everything installing it takes into
+// dir, which then can be archived or served as a mirror.
+func Export(ctx context.Context, opts Options, dir string) (*Index, error) {
+	// the releases seen while resolving the versions make up the catalog,
+	// so "latest" resolves to the bundled version in the cluster
+	catalog := mirror.Catalog{}
+	upstream := poller.GetSharedPoller()
+	poller.InitSharedGithubReleaseFakePoller(func(org, repo string) ([]string, error) {
+		releases, err := upstream.Get(org, repo)
+		if err == nil {
+			catalog[org+"/"+repo] = releases
+		}
+		return releases, err
+	})
+	defer poller.InitSharedGithubReleaseFakePoller(upstream.Get)
+
+	appStk, err := stacks.Get(ctx, logger.NewStructuredLogger(-1, os.Stdout), opts.StackName)
+	if err != nil {
+		return nil, err
+	}
+	params := stack.ApplicationParams{ComponentParams: map[stack.ComponentID]stack.ComponentOverrides{}}
+	for id, o := range opts.Overrides {
+		params.ComponentParams[stack.ComponentID(id)] = maps.Clone(o)
+	}
+	manifest, err := appStk(params)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := export(ctx, manifest, opts, dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeYAML(filepath.Join(dir, CatalogFile), catalog); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func export(ctx context.Context, manifest stack.ApplicationStack, opts Options, dir string) (*Index, error) {
+	index := &Index{
+		APIVersion:        APIVersion,
+		Version:           opts.Version,
+		Created:           time.Now().UTC(),
+		StackName:         opts.StackName,
+		DisableComponents: opts.DisableComponents,
+		Overrides:         opts.Overrides,
+	}
+	if len(index.Version) == 0 {
+		index.Version = index.Created.Format("20060102T150405Z")
+	}
+
+	chartsDir := filepath.Join(dir, mirror.ChartsDir)
+	if err := os.MkdirAll(chartsDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	for _, componentId := range manifest.StkDepsIdx {
+		if slices.Contains(opts.DisableComponents, string(componentId)) {
+			continue
+		}
+		v, ok := manifest.Components[componentId]
+		if !ok {
+			return nil, fmt.Errorf("component %s not found", componentId)
+		}
+
+		component := Component{
+			ID:          string(componentId),
+			HandlerType: string(v.HandlerType),
+			Version:     stacks.GetComponentVersionOverriding(v),
+		}
+		var err error
+		if v.HandlerType == stack.ComponentTypeKubectl {
+			component.Manifests, component.Images, err = exportManifests(ctx, v, dir)
+		} else {
+			component.Charts, component.Images, err = exportCharts(v, chartsDir)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", componentId, err)
+		}
+		index.Components = append(index.Components, component)
+		index.Images = append(index.Images, component.Images...)
+	}
+	slices.Sort(index.Images)
+	index.Images = slices.Compact(index.Images)
+
+	if err := writeChartIndex(chartsDir); err != nil {
+		return nil, err
+	}
+	if err := writeYAML(filepath.Join(dir, IndexFile), index); err != nil {
+		return nil, err
+	}
+	images := ""
+	for _, image := range index.Images {
+		images += image + "\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, ImagesFile), []byte(images), 0o644); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func exportManifests(ctx context.Context, v stack.Component, dir string) ([]Manifest, []string, error) {
+	var manifests []Manifest
+	var images []string
+	for _, u := range v.Kubectl.Urls {
+		p, err := mirror.ManifestPath(u)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw, err := fetch(ctx, u)
+		if err != nil {
+			return nil, nil, err
+		}
+		found, err := findImages(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("decoding %s: %w", u, err)
+		}
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(p)), raw); err != nil {
+			return nil, nil, err
+		}
+		manifests = append(manifests, Manifest{URL: u, Path: p, Digest: digest(raw)})
+		images = append(images, found...)
+	}
+	return manifests, images, nil
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func digest(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func writeFile(path string, raw []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o644)
+}
+
+func writeYAML(path string, v any) error {
+	raw, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, raw)
+}
+
+// ReadIndex reads the index of an unpacked bundle.
+func ReadIndex(dir string) (*Index, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{}
+	if err := yaml.Unmarshal(raw, index); err != nil {
+		return nil, err
+	}
+	if index.APIVersion != APIVersion {
+		return nil, fmt.Errorf("unsupported bundle %s, expected %s", index.APIVersion, APIVersion)
+	}
+	return index, nil
+}
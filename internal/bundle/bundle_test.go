@@ -0,0 +1,156 @@
+package bundle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/ksctl/ksctl/v2/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+
+	"github.com/ksctl/ka/internal/mirror"
+)
+
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operator
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+      containers:
+      - name: operator
+        image: ghcr.io/example/operator:v1.0.0
+---
+apiVersion: example.com/v1
+kind: Runtime
+metadata:
+  name: runtime
+spec:
+  image: ghcr.io/example/shim:v0.1.0
+`
+
+func TestFindImages(t *testing.T) {
+	images, err := findImages([]byte(testManifest))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"busybox:1.36", "ghcr.io/example/operator:v1.0.0", "ghcr.io/example/shim:v0.1.0"}, images)
+}
+
+// upstream serves a helm repository holding the demo chart scaffolded by
+// helm, and a manifest.
+func upstream(t *testing.T) *httptest.Server {
+	dir := t.TempDir()
+	chartDir, err := chartutil.Create("demo", t.TempDir())
+	require.NoError(t, err)
+	chrt, err := loader.Load(chartDir)
+	require.NoError(t, err)
+	_, err = chartutil.Save(chrt, filepath.Join(dir, "charts"))
+	require.NoError(t, err)
+	index, err := repo.IndexDirectory(filepath.Join(dir, "charts"), "")
+	require.NoError(t, err)
+	require.NoError(t, index.WriteFile(filepath.Join(dir, "charts", "index.yaml"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "releases", "v1.0.0"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "releases", "v1.0.0", "install.yaml"), []byte(testManifest), 0o644))
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestExport(t *testing.T) {
+	for _, env := range []string{"HELM_CACHE_HOME", "HELM_CONFIG_HOME", "HELM_DATA_HOME"} {
+		t.Setenv(env, t.TempDir())
+	}
+	srv := upstream(t)
+
+	manifest := stack.ApplicationStack{
+		StkDepsIdx: []stack.ComponentID{"demo", "operator", "disabled"},
+		Components: map[stack.ComponentID]stack.Component{
+			"demo": {
+				HandlerType: stack.ComponentTypeHelm,
+				Helm: &helm.App{
+					RepoUrl:  srv.URL + "/charts",
+					RepoName: "example",
+					Charts: []helm.ChartOptions{{
+						Name:        "example/demo",
+						Version:     "0.1.0",
+						ReleaseName: "demo",
+						Namespace:   "demo",
+						Args:        map[string]any{"image": map[string]any{"tag": "1.27.0"}},
+					}},
+				},
+			},
+			"operator": {
+				HandlerType: stack.ComponentTypeKubectl,
+				Kubectl: &k8s.App{
+					Urls:    []string{srv.URL + "/releases/v1.0.0/install.yaml"},
+					Version: "v1.0.0",
+				},
+			},
+			"disabled": {
+				HandlerType: stack.ComponentTypeKubectl,
+				Kubectl:     &k8s.App{Urls: []string{srv.URL + "/missing.yaml"}},
+			},
+		},
+	}
+
+	dir := t.TempDir()
+	index, err := export(context.Background(), manifest, Options{
+		StackName:         "example/demo",
+		DisableComponents: []string{"disabled"},
+		Version:           "1",
+	}, dir)
+	require.NoError(t, err)
+
+	require.Len(t, index.Components, 2)
+	assert.Equal(t, []Chart{{
+		Name:    "demo",
+		Version: "0.1.0",
+		Source:  srv.URL + "/charts",
+		Path:    "charts/demo-0.1.0.tgz",
+		Digest:  index.Components[0].Charts[0].Digest,
+	}}, index.Components[0].Charts)
+	// the scaffolded chart ships a test hook running busybox
+	assert.Equal(t, []string{"busybox", "nginx:1.27.0"}, index.Components[0].Images)
+	assert.Equal(t, []string{
+		"busybox", "busybox:1.36", "ghcr.io/example/operator:v1.0.0", "ghcr.io/example/shim:v0.1.0", "nginx:1.27.0",
+	}, index.Images)
+
+	// everything the mirror points the components at is in the bundle
+	m, err := mirror.New("http://mirror.local", "")
+	require.NoError(t, err)
+	mirrored, err := m.Rewrite(manifest)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, mirror.ChartsDir, mirror.ChartFile(mirrored.Components["demo"].Helm.Charts[0])))
+	assert.FileExists(t, filepath.Join(dir, mirror.ChartsDir, "index.yaml"))
+	p, err := mirror.ManifestPath(manifest.Components["operator"].Kubectl.Urls[0])
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(p)))
+
+	archive := filepath.Join(t.TempDir(), ArchiveName(index))
+	assert.Equal(t, "ka-bundle-example-demo-1.tar.gz", filepath.Base(archive))
+	require.NoError(t, Archive(dir, archive))
+
+	opened, err := Open(archive)
+	require.NoError(t, err)
+	defer os.RemoveAll(opened)
+	read, err := ReadIndex(opened)
+	require.NoError(t, err)
+	assert.Equal(t, index.Images, read.Images)
+	assert.FileExists(t, filepath.Join(opened, filepath.FromSlash(p)))
+	images, err := os.ReadFile(filepath.Join(opened, ImagesFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(images), "nginx:1.27.0\n")
+}
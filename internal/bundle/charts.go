@@ -0,0 +1,132 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/helm"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+
+	"github.com/ksctl/ka/internal/mirror"
+)
+
+func exportCharts(v stack.Component, chartsDir string) ([]Chart, []string, error) {
+	var charts []Chart
+	var images []string
+	for _, chart := range v.Helm.Charts {
+		path, source, err := pullChart(v.Helm, chart, chartsDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		found, err := chartImages(path, chart)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rendering %s: %w", chart.Name, err)
+		}
+		charts = append(charts, Chart{
+			Name:    mirror.ChartName(chart),
+			Version: chart.Version,
+			Source:  source,
+			Path:    filepath.ToSlash(filepath.Join(mirror.ChartsDir, filepath.Base(path))),
+			Digest:  digest(raw),
+		})
+		images = append(images, found...)
+	}
+	return charts, images, nil
+}
+
+// pullChart downloads the chart archive into the charts dir under the name
+// the mirror expects it.
+func pullChart(app *helm.App, chart helm.ChartOptions, chartsDir string) (string, string, error) {
+	registryClient, err := registry.NewClient(registry.ClientOptEnableCache(true))
+	if err != nil {
+		return "", "", err
+	}
+	tmp, err := os.MkdirTemp("", "ka-bundle-")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	pull := action.NewPullWithOpts(action.WithConfig(&action.Configuration{RegistryClient: registryClient}))
+	pull.Settings = cli.New()
+	pull.Version = chart.Version
+	pull.DestDir = tmp
+
+	ref, source := chart.ChartRef, chart.ChartRef
+	if len(ref) == 0 {
+		pull.RepoURL = app.RepoUrl
+		ref, source = mirror.ChartName(chart), app.RepoUrl
+	}
+	if _, err := pull.Run(ref); err != nil {
+		return "", "", fmt.Errorf("pulling %s %s: %w", ref, chart.Version, err)
+	}
+
+	pulled, err := filepath.Glob(filepath.Join(tmp, "*.tgz"))
+	if err != nil {
+		return "", "", err
+	}
+	if len(pulled) != 1 {
+		return "", "", fmt.Errorf("pulling %s %s: expected a single archive, got %d", ref, chart.Version, len(pulled))
+	}
+	raw, err := os.ReadFile(pulled[0])
+	if err != nil {
+		return "", "", err
+	}
+	dest := filepath.Join(chartsDir, mirror.ChartFile(chart))
+	return dest, source, os.WriteFile(dest, raw, 0o644)
+}
+
+// chartImages renders the chart with the values of the component the way
+// `helm template` does and lists the images of the output.
+func chartImages(path string, chart helm.ChartOptions) ([]string, error) {
+	chrt, err := loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	install := action.NewInstall(&action.Configuration{Log: func(string, ...any) {}})
+	install.DryRun = true
+	install.ClientOnly = true
+	install.Replace = true
+	install.IncludeCRDs = true
+	install.ReleaseName = chart.ReleaseName
+	install.Namespace = chart.Namespace
+
+	rel, err := install.Run(chrt, chart.Args)
+	if err != nil {
+		return nil, err
+	}
+	images, err := findImages([]byte(rel.Manifest))
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range rel.Hooks {
+		found, err := findImages([]byte(hook.Manifest))
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, found...)
+	}
+	slices.Sort(images)
+	return slices.Compact(images), nil
+}
+
+// writeChartIndex turns the charts dir into a helm repository.
+func writeChartIndex(chartsDir string) error {
+	index, err := repo.IndexDirectory(chartsDir, "")
+	if err != nil {
+		return err
+	}
+	index.SortEntries()
+	return index.WriteFile(filepath.Join(chartsDir, "index.yaml"), 0o644)
+}
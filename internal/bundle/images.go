@@ -0,0 +1,47 @@
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// findImages lists the images of a multi-document YAML (or JSON) manifest:
+// every string under an "image" key, which covers the pod templates of the
+// workloads as well as the custom resources of operators.
+func findImages(raw []byte) ([]string, error) {
+	var images []string
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+	for {
+		var doc any
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		images = appendImages(images, doc)
+	}
+	slices.Sort(images)
+	return slices.Compact(images), nil
+}
+
+func appendImages(images []string, v any) []string {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if image, ok := val.(string); ok && key == "image" && len(image) != 0 {
+				images = append(images, image)
+				continue
+			}
+			images = appendImages(images, val)
+		}
+	case []any:
+		for _, val := range v {
+			images = appendImages(images, val)
+		}
+	}
+	return images
+}
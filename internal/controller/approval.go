@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+var ErrApprovalPending = errors.New("plan waits for approval")
+
+func isPlanApproved(app *appv1.Stack, plan *appv1.StackPlan) bool {
+	return plan != nil && len(plan.Hash) != 0 && app.Annotations[appv1.ApprovedPlanAnnotation] == plan.Hash
+}
+
+// checkApproval holds back the steps of a Stack requiring approval until the
+// annotation approves their plan. Without a plan for the current spec one gets
+// recorded in the status for review, plans only skipping components pass.
+func checkApproval(app *appv1.Stack, plan *appv1.StackPlan, steps []appv1.PlanStep) error {
+	if !app.Spec.RequireApproval || !hasChanges(steps) {
+		return nil
+	}
+	if plan == nil {
+		var err error
+		if plan, err = newStackPlan(app, steps); err != nil {
+			return err
+		}
+		app.Status.Plan = plan
+	}
+	if isPlanApproved(app, plan) {
+		return nil
+	}
+	return fmt.Errorf("%w: annotate with %s=%s to apply it", ErrApprovalPending, appv1.ApprovedPlanAnnotation, plan.Hash)
+}
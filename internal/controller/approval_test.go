@@ -0,0 +1,55 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+var _ = Describe("Stack approval", func() {
+	steps := []appv1.PlanStep{
+		{ID: "argocd", Action: appv1.PlanInstall, Version: "v2.14.3"},
+	}
+
+	It("should record a plan and wait until its hash is approved", func() {
+		app := newStack("gitops", "gitops-standard")
+		Expect(checkApproval(app, nil, steps)).To(Succeed())
+		Expect(app.Status.Plan).To(BeNil())
+
+		app.Spec.RequireApproval = true
+		Expect(checkApproval(app, nil, steps)).To(MatchError(ErrApprovalPending))
+		Expect(app.Status.Plan).NotTo(BeNil())
+		Expect(app.Status.Plan.Steps).To(Equal(steps))
+
+		plan := app.Status.Plan
+		app.Annotations = map[string]string{appv1.ApprovedPlanAnnotation: "something-else"}
+		Expect(checkApproval(app, plan, steps)).To(MatchError(ErrApprovalPending))
+
+		app.Annotations[appv1.ApprovedPlanAnnotation] = plan.Hash
+		Expect(checkApproval(app, plan, steps)).To(Succeed())
+	})
+
+	It("should not ask approval for plans which change nothing", func() {
+		app := newStack("gitops", "gitops-standard")
+		app.Spec.RequireApproval = true
+
+		Expect(checkApproval(app, nil, []appv1.PlanStep{{ID: "argocd", Action: appv1.PlanSkip}})).To(Succeed())
+		Expect(app.Status.Plan).To(BeNil())
+	})
+
+	It("should invalidate the approval when the spec changes", func() {
+		app := newStack("gitops", "gitops-standard")
+		app.Spec.RequireApproval = true
+		approved, err := newStackPlan(app, steps)
+		Expect(err).NotTo(HaveOccurred())
+		app.Status.Plan = approved
+		app.Annotations = map[string]string{appv1.ApprovedPlanAnnotation: approved.Hash}
+		Expect(appliedPlan(app)).To(Equal(approved))
+
+		app.Spec.DisableComponents = []string{"argo-rollouts"}
+		Expect(appliedPlan(app)).To(BeNil())
+		Expect(checkApproval(app, nil, steps)).To(MatchError(ErrApprovalPending))
+		Expect(app.Status.Plan.Hash).NotTo(Equal(approved.Hash))
+	})
+})
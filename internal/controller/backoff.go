@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+const (
+	DefaultMaxRetries int32 = 10
+
+	backoffBase   time.Duration = 5 * time.Second
+	backoffCap    time.Duration = 10 * time.Minute
+	backoffJitter float64       = 0.2
+)
+
+// retryBackoff is how long to wait before the given retry, it doubles with
+// every retry up to backoffCap and is spread by up to backoffJitter so stacks
+// failing together don't retry together.
+func retryBackoff(retry int32) time.Duration {
+	d := backoffBase
+	for i := int32(1); i < retry && d < backoffCap; i++ {
+		d *= 2
+	}
+	return wait.Jitter(min(d, backoffCap), backoffJitter)
+}
+
+// recordFailure counts the failed attempt and returns when to retry, zero once
+// the retries are exhausted and the stack stalled. The failure is recorded
+// along with the generation it happened at, unless the failure policy already
+// did, so changing the Stack starts over.
+func (r *StackReconciler) recordFailure(app *appv1.Stack, err error) time.Duration {
+	failure := app.Status.LastFailure
+	if failure == nil || failure.ObservedGeneration != app.Generation {
+		app.Status.LastFailure = &appv1.FailureStatus{Action: appv1.FailureRetry, ObservedGeneration: app.Generation}
+	}
+	if failure = app.Status.LastFailure; failure.Action == appv1.FailureRetry {
+		failure.Message = err.Error()
+		failure.Time = metav1.Now()
+	}
+	app.Status.RetryCount++
+
+	if r.MaxRetries > 0 && app.Status.RetryCount > r.MaxRetries {
+		app.Status.NextRetryTime = nil
+		setStackCondition(app, appv1.ConditionStalled, metav1.ConditionTrue, ReasonRetriesExhausted,
+			fmt.Sprintf("Gave up after %d retries, set the %s annotation to retry: %v",
+				r.MaxRetries, appv1.RetryAnnotation, err))
+		return 0
+	}
+
+	d := retryBackoff(app.Status.RetryCount)
+	next := metav1.NewTime(time.Now().Add(d))
+	app.Status.NextRetryTime = &next
+	return d
+}
+
+func resetRetries(app *appv1.Stack) {
+	app.Status.RetryCount = 0
+	app.Status.NextRetryTime = nil
+	meta.RemoveStatusCondition(&app.Status.Conditions, appv1.ConditionStalled)
+}
+
+// consumeRetryAnnotation removes the retry annotation from the Stack and
+// forgets about the previous failures, it tells whether there was one.
+func (r *StackReconciler) consumeRetryAnnotation(ctx context.Context, app *appv1.Stack) (bool, error) {
+	if _, ok := app.Annotations[appv1.RetryAnnotation]; !ok {
+		return false, nil
+	}
+	log.FromContext(ctx).Info("Retry forced", "stack", app.Name)
+
+	delete(app.Annotations, appv1.RetryAnnotation)
+	if err := r.Update(ctx, app); err != nil {
+		return false, err
+	}
+	resetRetries(app)
+	app.Status.LastFailure = nil
+	return true, nil
+}
+
+// retriesOutdated tells whether the failures counted so far are of another
+// spec of the Stack, or of an attempt made before the Stack got deleted. The
+// backoff then starts over, see resetRetries.
+func retriesOutdated(app *appv1.Stack) bool {
+	if failure := app.Status.LastFailure; failure != nil {
+		if !app.DeletionTimestamp.IsZero() && failure.Time.Before(app.DeletionTimestamp) {
+			return true
+		}
+		if failure.ObservedGeneration != app.Generation {
+			return true
+		}
+	}
+	stalled := meta.FindStatusCondition(app.Status.Conditions, appv1.ConditionStalled)
+	return stalled != nil && stalled.Status == metav1.ConditionTrue && stalled.ObservedGeneration != app.Generation
+}
+
+// retryDelay is how long the failing stack still has to wait before the next
+// attempt, it is never reconciled again once stalled.
+func retryDelay(app *appv1.Stack) (time.Duration, bool) {
+	if meta.IsStatusConditionTrue(app.Status.Conditions, appv1.ConditionStalled) {
+		return 0, true
+	}
+	if app.Status.NextRetryTime == nil {
+		return 0, false
+	}
+	return max(time.Until(app.Status.NextRetryTime.Time), 0), false
+}
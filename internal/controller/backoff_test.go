@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+var _ = Describe("Stack retries", func() {
+	It("should back off exponentially up to the cap", func() {
+		for retry, base := range map[int32]time.Duration{
+			1:  backoffBase,
+			2:  2 * backoffBase,
+			4:  8 * backoffBase,
+			50: backoffCap,
+		} {
+			d := retryBackoff(retry)
+			Expect(d).To(BeNumerically(">=", base))
+			Expect(d).To(BeNumerically("<=", time.Duration(float64(base)*(1+backoffJitter))))
+		}
+	})
+
+	It("should stall once the retries are exhausted", func() {
+		r := &StackReconciler{MaxRetries: 2}
+		app := newStack("istio", "service-mesh-standard")
+
+		Expect(r.recordFailure(app, errors.New("boom"))).To(BeNumerically(">", 0))
+		Expect(app.Status.NextRetryTime).NotTo(BeNil())
+		delay, stalled := retryDelay(app)
+		Expect(stalled).To(BeFalse())
+		Expect(delay).To(BeNumerically(">", 0))
+
+		Expect(r.recordFailure(app, errors.New("boom"))).To(BeNumerically(">", 0))
+		Expect(r.recordFailure(app, errors.New("boom"))).To(BeZero())
+		Expect(app.Status.RetryCount).To(BeEquivalentTo(3))
+		Expect(app.Status.NextRetryTime).To(BeNil())
+		_, stalled = retryDelay(app)
+		Expect(stalled).To(BeTrue())
+
+		resetRetries(app)
+		Expect(meta.FindStatusCondition(app.Status.Conditions, appv1.ConditionStalled)).To(BeNil())
+	})
+
+	It("should start over once the spec changes", func() {
+		r := &StackReconciler{MaxRetries: 1}
+		app := newStack("istio", "service-mesh-standard")
+		app.Generation = 1
+		app.Status.LastFailure = &appv1.FailureStatus{Action: appv1.FailureRetry, ObservedGeneration: 1}
+		r.recordFailure(app, errors.New("boom"))
+		r.recordFailure(app, errors.New("boom"))
+
+		_, stalled := retryDelay(app)
+		Expect(stalled).To(BeTrue())
+		Expect(retriesOutdated(app)).To(BeFalse())
+
+		app.Generation = 2
+		_, stalled = retryDelay(app)
+		Expect(stalled).To(BeTrue(), "the delay leaves the status alone")
+		Expect(retriesOutdated(app)).To(BeTrue())
+	})
+
+	It("should record failures which stall without a failure policy", func() {
+		r := &StackReconciler{MaxRetries: 1}
+		app := newStack("istio", "service-mesh-standard")
+		app.Generation = 1
+		r.recordFailure(app, errors.New("plan failed"))
+		r.recordFailure(app, errors.New("plan failed again"))
+
+		Expect(app.Status.LastFailure).NotTo(BeNil())
+		Expect(app.Status.LastFailure.Action).To(Equal(appv1.FailureRetry))
+		Expect(app.Status.LastFailure.Message).To(Equal("plan failed again"))
+		Expect(app.Status.LastFailure.ObservedGeneration).To(BeEquivalentTo(1))
+		Expect(isInstallHalted(app)).To(BeFalse())
+
+		app.Generation = 2
+		Expect(retriesOutdated(app)).To(BeTrue())
+		app.Status.LastFailure = nil
+		Expect(retriesOutdated(app)).To(BeTrue(), "the Stalled condition is of the old generation as well")
+	})
+
+	It("should start over when a Stack which failed to install gets deleted", func() {
+		r := &StackReconciler{MaxRetries: 3}
+		app := newStack("istio", "service-mesh-standard")
+		r.recordFailure(app, errors.New("install failed"))
+		Expect(retriesOutdated(app)).To(BeFalse())
+
+		deleted := metav1.NewTime(time.Now().Add(time.Second))
+		app.DeletionTimestamp = &deleted
+		Expect(retriesOutdated(app)).To(BeTrue())
+
+		app.Status.LastFailure = nil
+		resetRetries(app)
+		app.Status.LastFailure = &appv1.FailureStatus{Action: appv1.FailureRetry, Time: metav1.NewTime(deleted.Add(time.Second))}
+		Expect(retriesOutdated(app)).To(BeFalse(), "uninstall failures keep backing off")
+	})
+
+	It("should consume the retry annotation", func() {
+		app := newStack("istio", "service-mesh-standard")
+		app.Annotations = map[string]string{appv1.RetryAnnotation: "now"}
+		c := newFakeClient(app)
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(app), app)).To(Succeed())
+
+		app.Status.RetryCount = 4
+		next := metav1.Now()
+		app.Status.NextRetryTime = &next
+		app.Status.LastFailure = &appv1.FailureStatus{Action: appv1.FailurePause}
+
+		r := &StackReconciler{Client: c}
+		forced, err := r.consumeRetryAnnotation(context.Background(), app)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(forced).To(BeTrue())
+		Expect(app.Status.RetryCount).To(BeZero())
+		Expect(app.Status.LastFailure).To(BeNil())
+
+		stored := &appv1.Stack{}
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(app), stored)).To(Succeed())
+		Expect(stored.Annotations).NotTo(HaveKey(appv1.RetryAnnotation))
+
+		forced, err = r.consumeRetryAnnotation(context.Background(), app)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(forced).To(BeFalse())
+	})
+})
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+var ErrStackConflict = errors.New("conflicting stack")
+
+// componentClaims returns what the component takes ownership of in the cluster:
+// its helm releases and the namespaces it creates.
+func componentClaims(v stack.Component) (releases, namespaces []string) {
+	switch v.HandlerType {
+	case stack.ComponentTypeHelm:
+		if v.Helm == nil {
+			return nil, nil
+		}
+		for _, chart := range v.Helm.Charts {
+			releases = append(releases, chart.Namespace+"/"+chart.ReleaseName)
+			if chart.CreateNamespace {
+				namespaces = append(namespaces, chart.Namespace)
+			}
+		}
+	case stack.ComponentTypeKubectl:
+		if v.Kubectl != nil && v.Kubectl.CreateNamespace && len(v.Kubectl.Namespace) != 0 {
+			namespaces = append(namespaces, v.Kubectl.Namespace)
+		}
+	}
+	return releases, namespaces
+}
+
+func newComponentState(v stack.Component, ver, hash string) ComponentState {
+	releases, namespaces := componentClaims(v)
+	return ComponentState{
+		Ver:        ver,
+		Hash:       hash,
+		Releases:   releases,
+		Namespaces: namespaces,
+	}
+}
+
+// findConflict checks the components the Stack object recorded under key is
+// about to install against the ones installed by every other Stack object, two
+// of them must never share a helm release or a namespace they create.
+func findConflict(state *StackState, key string, manifest stack.ApplicationStack, disabled []string) error {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+
+	for _, componentId := range manifest.StkDepsIdx {
+		if slices.Contains(disabled, string(componentId)) {
+			continue
+		}
+		v, ok := manifest.Components[componentId]
+		if !ok {
+			continue
+		}
+		releases, namespaces := componentClaims(v)
+
+		for _, other := range slices.Sorted(maps.Keys(state.Stacks)) {
+			if other == key {
+				continue
+			}
+			otherState := state.Stacks[other]
+			for _, otherComponentId := range slices.Sorted(maps.Keys(otherState.Components)) {
+				otherComponent := otherState.Components[otherComponentId]
+				for _, release := range releases {
+					if slices.Contains(otherComponent.Releases, release) {
+						return fmt.Errorf("%w: component %s installs helm release %s already owned by component %s of Stack %s",
+							ErrStackConflict, componentId, release, otherComponentId, other)
+					}
+				}
+				for _, ns := range namespaces {
+					if slices.Contains(otherComponent.Namespaces, ns) {
+						return fmt.Errorf("%w: component %s creates namespace %s already owned by component %s of Stack %s",
+							ErrStackConflict, componentId, ns, otherComponentId, other)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/ksctl/ksctl/v2/pkg/k8s"
+)
+
+func monitoringManifest(ns string) stack.ApplicationStack {
+	return stack.ApplicationStack{
+		StkDepsIdx: []stack.ComponentID{"kube-prometheus"},
+		Components: map[stack.ComponentID]stack.Component{
+			"kube-prometheus": {
+				HandlerType: stack.ComponentTypeHelm,
+				Helm: &helm.App{
+					Charts: []helm.ChartOptions{{
+						ReleaseName:     "kube-prometheus-stack",
+						Namespace:       ns,
+						CreateNamespace: true,
+					}},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Stack conflicts", func() {
+	It("should record the helm releases and created namespaces of a component", func() {
+		releases, namespaces := componentClaims(monitoringManifest("monitoring").Components["kube-prometheus"])
+		Expect(releases).To(Equal([]string{"monitoring/kube-prometheus-stack"}))
+		Expect(namespaces).To(Equal([]string{"monitoring"}))
+
+		releases, namespaces = componentClaims(stack.Component{
+			HandlerType: stack.ComponentTypeKubectl,
+			Kubectl:     &k8s.App{Namespace: "argocd", CreateNamespace: true},
+		})
+		Expect(releases).To(BeEmpty())
+		Expect(namespaces).To(Equal([]string{"argocd"}))
+	})
+
+	It("should only report conflicts with other Stack objects", func() {
+		installed := monitoringManifest("monitoring").Components["kube-prometheus"]
+		state := &StackState{Stacks: map[string]AppState{
+			"monitoring-a": {
+				StackName: "monitoring-lite",
+				Components: map[string]ComponentState{
+					"kube-prometheus": newComponentState(installed, "72.6.2", ""),
+				},
+			},
+		}}
+
+		Expect(findConflict(state, "monitoring-a", monitoringManifest("monitoring"), nil)).To(Succeed())
+		Expect(findConflict(state, "monitoring-b", monitoringManifest("monitoring-b"), nil)).To(Succeed())
+		Expect(findConflict(state, "monitoring-b", monitoringManifest("monitoring"), []string{"kube-prometheus"})).To(Succeed())
+
+		err := findConflict(state, "monitoring-b", monitoringManifest("monitoring"), nil)
+		Expect(err).To(MatchError(ErrStackConflict))
+		Expect(err.Error()).To(ContainSubstring("monitoring/kube-prometheus-stack"))
+	})
+
+	It("should hand state keyed by stackName to the oldest Stack object", func() {
+		state := &StackState{Stacks: map[string]AppState{
+			"monitoring-lite": {Components: map[string]ComponentState{"kube-prometheus": {Ver: "72.6.2"}}},
+		}}
+		newer := newStack("monitoring-b", "monitoring-lite")
+		newer.CreationTimestamp = metav1.Unix(200, 0)
+		older := newStack("monitoring-a", "monitoring-lite")
+		older.CreationTimestamp = metav1.Unix(100, 0)
+
+		adopted := adoptLegacyRecords(state, []appv1.Stack{*newer, *older})
+		Expect(adopted).To(HaveLen(1))
+		Expect(adopted[0].Name).To(Equal("monitoring-a"))
+		Expect(state.Stacks).To(HaveLen(1))
+		Expect(state.Stacks["monitoring-a"].UID).To(BeEquivalentTo("uid-monitoring-a"))
+		Expect(state.Stacks["monitoring-a"].StackName).To(Equal("monitoring-lite"))
+
+		r := &StackReconciler{state: state}
+		Expect(r.StateKey(newer)).To(Equal("monitoring-b"))
+		Expect(r.WasStackInstalled("monitoring-b")).To(BeFalse())
+		Expect(r.WasComponentInstalled("monitoring-a", "kube-prometheus")).To(BeTrue())
+	})
+})
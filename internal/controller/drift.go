@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/executor"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+)
+
+func getDriftPolicy(app *appv1.Stack) appv1.DriftPolicy {
+	if len(app.Spec.DriftPolicy) == 0 {
+		return appv1.DriftReport
+	}
+	return app.Spec.DriftPolicy
+}
+
+// driftCheckInterval is how long until the stack gets checked for drift again,
+// zero when it shouldn't be.
+func (r *StackReconciler) driftCheckInterval(app *appv1.Stack) time.Duration {
+	if getDriftPolicy(app) == appv1.DriftIgnore {
+		return 0
+	}
+	return r.DriftCheckInterval
+}
+
+// nextDriftCheckIn is how long until the stack is due for its next drift
+// check, zero when it doesn't get checked.
+func (r *StackReconciler) nextDriftCheckIn(app *appv1.Stack, now time.Time) time.Duration {
+	interval := r.driftCheckInterval(app)
+	last := app.Status.LastDriftCheckTime
+	if interval == 0 || last == nil {
+		return interval
+	}
+	return max(last.Add(interval).Sub(now), time.Second)
+}
+
+// reportedDrift is the drift found by the last check, which stands until the
+// next one.
+func reportedDrift(app *appv1.Stack) []string {
+	cond := meta.FindStatusCondition(app.Status.Conditions, appv1.ConditionDrifted)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		return nil
+	}
+	return strings.Split(cond.Message, "\n")
+}
+
+func (r *StackReconciler) componentDrift(ctx context.Context, v stack.Component) ([]string, error) {
+	if v.HandlerType == stack.ComponentTypeKubectl {
+		return executor.K8sDriftHandler(ctx, r.RestConfig, v.Kubectl)
+	}
+	return executor.HelmDriftHandler(ctx, r.RestConfig, v.Helm)
+}
+
+// checkDrift compares every installed component of the stack with the cluster.
+// With the heal policy drifted components get redeployed, otherwise they are
+// returned along with what drifted.
+func (r *StackReconciler) checkDrift(ctx context.Context, app *appv1.Stack) (drifted []string, healed []string, err error) {
+	l := log.FromContext(ctx)
+	kl := logger.NewStructuredLogger(-1, os.Stdout)
+
+	key := r.StateKey(app)
+	appState, ok := r.state.get(key)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	overrides, err := getStackOverrides(app)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest, err := getStackManifest(kl, app.Spec.StackName, getDesiredOverrides(overrides, appState))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	heal := getDriftPolicy(app) == appv1.DriftHeal
+	if heal {
+		// healing redeploys like an install does
+		unlock, err := r.locks.lock(ctx, stackResources(manifest))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer unlock()
+	}
+
+	redeployed := false
+	defer func() {
+		r.state.put(key, appState)
+		if !redeployed {
+			return
+		}
+		if err := r.Save(ctx, app); err != nil {
+			l.Error(err, "Failed to save state")
+		}
+	}()
+
+	for _, componentId := range manifest.StkDepsIdx {
+		if _, ok := appState.Components[string(componentId)]; !ok {
+			continue
+		}
+		v, ok := manifest.Components[componentId]
+		if !ok {
+			continue
+		}
+
+		drift, err := r.componentDrift(ctx, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(drift) == 0 {
+			continue
+		}
+		reason := strings.Join(drift, "; ")
+		ver := stacks.GetComponentVersionOverriding(v)
+		l.Info("Component drifted", "component", componentId, "stack", app.Spec.StackName, "drift", reason)
+
+		if heal {
+			setComponentPhase(app, componentId, v, ver, appv1.ComponentInstalling, nil)
+			r.componentEvent(app, EventInstalling, componentId, ver, nil)
+			start := time.Now()
+			redeployed = true
+			if err := r.upgradeComponent(ctx, v); err != nil {
+				setComponentPhase(app, componentId, v, ver, appv1.ComponentFailed, err)
+				r.componentEvent(app, EventInstallFailed, componentId, ver, err)
+				return nil, nil, err
+			}
+			if err := r.awaitComponent(ctx, nil, app, appState, componentId, v, ver, appState.Components[string(componentId)]); err != nil {
+				r.componentEvent(app, EventInstallFailed, componentId, ver, err)
+				return nil, nil, err
+			}
+			setComponentPhase(app, componentId, v, ver, appv1.ComponentInstalled, nil)
+			observeInstall(app, componentId, start)
+			r.componentEvent(app, EventInstalled, componentId, ver, nil)
+			healed = append(healed, string(componentId))
+			continue
+		}
+
+		updateComponentStatus(app, componentId, func(c *appv1.ComponentStatus) {
+			c.Phase = appv1.ComponentDrifted
+			c.LastError = reason
+		})
+		drifted = append(drifted, fmt.Sprintf("%s: %s", componentId, reason))
+	}
+	return drifted, healed, nil
+}
+
+// processDrift runs the drift check the drift policy asks for once the drift
+// check interval passed and reflects it in the Drifted condition, it returns
+// the drift left in place.
+func (r *StackReconciler) processDrift(ctx context.Context, app *appv1.Stack, now time.Time) ([]string, error) {
+	interval := r.driftCheckInterval(app)
+	if interval == 0 {
+		meta.RemoveStatusCondition(&app.Status.Conditions, appv1.ConditionDrifted)
+		app.Status.LastDriftCheckTime = nil
+		return nil, nil
+	}
+	if last := app.Status.LastDriftCheckTime; last != nil && now.Before(last.Add(interval)) {
+		return reportedDrift(app), nil
+	}
+
+	drifted, healed, err := r.checkDrift(ctx, app)
+	if err != nil {
+		setStackCondition(app, appv1.ConditionDrifted, metav1.ConditionUnknown, ReasonDriftCheckFailed, err.Error())
+		return nil, err
+	}
+	app.Status.LastDriftCheckTime = &metav1.Time{Time: now}
+	switch {
+	case len(drifted) != 0:
+		setStackCondition(app, appv1.ConditionDrifted, metav1.ConditionTrue, ReasonDriftDetected, strings.Join(drifted, "\n"))
+	case len(healed) != 0:
+		setStackCondition(app, appv1.ConditionDrifted, metav1.ConditionFalse, ReasonDriftHealed,
+			"Reinstalled drifted components: "+strings.Join(healed, ", "))
+	default:
+		setStackCondition(app, appv1.ConditionDrifted, metav1.ConditionFalse, ReasonNoDrift, "Installed components match the cluster")
+	}
+	return drifted, nil
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+)
+
+var _ = Describe("Stack drift", func() {
+	It("should only requeue stacks which get checked for drift", func() {
+		r := &StackReconciler{DriftCheckInterval: 5 * time.Minute}
+
+		app := &appv1.Stack{}
+		Expect(getDriftPolicy(app)).To(Equal(appv1.DriftReport))
+		Expect(r.driftCheckInterval(app)).To(Equal(5 * time.Minute))
+
+		app.Spec.DriftPolicy = appv1.DriftHeal
+		Expect(r.driftCheckInterval(app)).To(Equal(5 * time.Minute))
+
+		app.Spec.DriftPolicy = appv1.DriftIgnore
+		Expect(r.driftCheckInterval(app)).To(BeZero())
+	})
+
+	It("should not check stacks which are not installed", func() {
+		r := &StackReconciler{DriftCheckInterval: 5 * time.Minute, state: &StackState{Stacks: map[string]AppState{}}}
+		app := newStack("monitoring-a", "monitoring-lite")
+
+		drifted, err := r.processDrift(context.Background(), app, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifted).To(BeEmpty())
+		Expect(app.Status.Conditions).To(ContainElement(HaveField("Reason", ReasonNoDrift)))
+	})
+
+	It("should only check for drift once the interval passed", func() {
+		now := time.Date(2026, time.October, 17, 3, 0, 0, 0, time.UTC)
+		r := &StackReconciler{DriftCheckInterval: 5 * time.Minute, state: &StackState{Stacks: map[string]AppState{}}}
+		app := newStack("monitoring-a", "monitoring-lite")
+
+		_, err := r.processDrift(context.Background(), app, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(app.Status.LastDriftCheckTime.Time).To(Equal(now))
+		Expect(r.nextDriftCheckIn(app, now.Add(time.Minute))).To(Equal(4 * time.Minute))
+
+		// the drift reported by the last check stands until the next one
+		setStackCondition(app, appv1.ConditionDrifted, metav1.ConditionTrue, ReasonDriftDetected, "a: missing\nb: missing")
+		drifted, err := r.processDrift(context.Background(), app, now.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifted).To(Equal([]string{"a: missing", "b: missing"}))
+		Expect(app.Status.LastDriftCheckTime.Time).To(Equal(now))
+
+		drifted, err = r.processDrift(context.Background(), app, now.Add(5*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifted).To(BeEmpty())
+		Expect(app.Status.LastDriftCheckTime.Time).To(Equal(now.Add(5 * time.Minute)))
+		Expect(app.Status.Conditions).To(ContainElement(HaveField("Reason", ReasonNoDrift)))
+	})
+
+	It("should not check for drift without an interval", func() {
+		r := &StackReconciler{state: &StackState{Stacks: map[string]AppState{}}}
+		app := newStack("monitoring-a", "monitoring-lite")
+		app.Status.LastDriftCheckTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+		setStackCondition(app, appv1.ConditionDrifted, metav1.ConditionTrue, ReasonDriftDetected, "a: missing")
+
+		drifted, err := r.processDrift(context.Background(), app, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifted).To(BeEmpty())
+		Expect(app.Status.LastDriftCheckTime).To(BeNil())
+		Expect(meta.FindStatusCondition(app.Status.Conditions, appv1.ConditionDrifted)).To(BeNil())
+		Expect(r.nextDriftCheckIn(app, time.Now())).To(BeZero())
+	})
+
+	It("should wait for the resources of the stack before healing", func() {
+		appState := AppState{Components: map[string]ComponentState{"kube-prometheus": {Ver: "72.6.2"}}}
+		r := &StackReconciler{state: &StackState{Stacks: map[string]AppState{"monitoring": appState}}}
+		app := newStack("monitoring", "monitoring-lite")
+		app.Spec.DriftPolicy = appv1.DriftHeal
+
+		manifest, err := getStackManifest(logger.NewStructuredLogger(-1, os.Stdout), "monitoring-lite",
+			getDesiredOverrides(nil, appState))
+		Expect(err).NotTo(HaveOccurred())
+		unlock, err := r.locks.lock(context.Background(), stackResources(manifest))
+		Expect(err).NotTo(HaveOccurred())
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, _, err = r.checkDrift(ctx, app)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+const (
+	EventInstalling           string = "Installing"
+	EventInstalled            string = "Installed"
+	EventInstallFailed        string = "InstallFailed"
+	EventUpgrading            string = "Upgrading"
+	EventUpgraded             string = "Upgraded"
+	EventUpgradeFailed        string = "UpgradeFailed"
+	EventUninstalling         string = "Uninstalling"
+	EventUninstalled          string = "Uninstalled"
+	EventUninstallFailed      string = "UninstallFailed"
+	EventSkipped              string = "Skipped"
+	EventNodesAnnotated       string = "NodesAnnotated"
+	EventNodeAnnotationFailed string = "NodeAnnotationFailed"
+	EventFinalizerAdded       string = "FinalizerAdded"
+	EventFinalizerRemoved     string = "FinalizerRemoved"
+	EventUpdateAvailable      string = "UpdateAvailable"
+	EventUpdateCheckFailed    string = "UpdateCheckFailed"
+)
+
+// event records an event on the Stack, nothing happens without a recorder.
+func (r *StackReconciler) event(app *appv1.Stack, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(app, eventType, reason, messageFmt, args...)
+}
+
+// componentEvent records an event about a single component of the Stack, a
+// failure turns it into a warning carrying the error.
+func (r *StackReconciler) componentEvent(app *appv1.Stack, reason string, componentId stack.ComponentID, ver string, err error) {
+	if err != nil {
+		r.event(app, corev1.EventTypeWarning, reason, "Component %s version %s: %v", componentId, ver, err)
+		return
+	}
+	r.event(app, corev1.EventTypeNormal, reason, "Component %s version %s", componentId, ver)
+}
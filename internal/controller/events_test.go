@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("Stack events", func() {
+	It("should carry the component and its version", func() {
+		recorder := record.NewFakeRecorder(4)
+		r := &StackReconciler{Recorder: recorder}
+		app := newStack("gitops", "gitops-standard")
+
+		r.componentEvent(app, EventInstalling, "argocd", "v2.14.2", nil)
+		r.componentEvent(app, EventInstallFailed, "argocd", "v2.14.2", errors.New("boom"))
+
+		Expect(recorder.Events).To(Receive(Equal("Normal Installing Component argocd version v2.14.2")))
+		Expect(recorder.Events).To(Receive(Equal("Warning InstallFailed Component argocd version v2.14.2: boom")))
+	})
+
+	It("should record the finalizer being added", func() {
+		app := newStack("gitops", "gitops-standard")
+		recorder := record.NewFakeRecorder(1)
+		r := &StackReconciler{Client: newFakeClient(app), Recorder: recorder}
+
+		_, err := r.addFinalizer(context.Background(), app)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring(EventFinalizerAdded)))
+	})
+
+	It("should not need a recorder", func() {
+		r := &StackReconciler{}
+		r.componentEvent(newStack("gitops", "gitops-standard"), EventInstalled, "argocd", "v2.14.2", nil)
+	})
+})
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+)
+
+func getFailurePolicy(app *appv1.Stack) appv1.FailurePolicy {
+	if len(app.Spec.FailurePolicy) == 0 {
+		return appv1.FailureRetry
+	}
+	return app.Spec.FailurePolicy
+}
+
+// isInstallHalted tells whether the failure policy stopped installing the
+// stack, it stays that way until the Stack changes.
+func isInstallHalted(app *appv1.Stack) bool {
+	failure := app.Status.LastFailure
+	return failure != nil &&
+		failure.Action != appv1.FailureRetry &&
+		failure.ObservedGeneration == app.Generation
+}
+
+// handleInstallFailure applies the failure policy to the failed install
+// attempt and records what was done in the status.
+func (r *StackReconciler) handleInstallFailure(
+	ctx context.Context,
+	app *appv1.Stack,
+	installed []stack.ComponentID,
+	installErr error,
+) {
+	failure := &appv1.FailureStatus{
+		Action:             getFailurePolicy(app),
+		Message:            installErr.Error(),
+		ObservedGeneration: app.Generation,
+		Time:               metav1.Now(),
+	}
+	app.Status.LastFailure = failure
+
+	switch failure.Action {
+	case appv1.FailureRollback:
+		rolledBack, err := r.rollback(ctx, app, installed)
+		failure.RolledBack = rolledBack
+		if err != nil {
+			failure.RollbackError = err.Error()
+			setStackCondition(app, appv1.ConditionReady, metav1.ConditionFalse, ReasonRollbackFailed, err.Error())
+			return
+		}
+		setStackCondition(app, appv1.ConditionReady, metav1.ConditionFalse, ReasonRolledBack, installErr.Error())
+
+	case appv1.FailurePause:
+		setStackCondition(app, appv1.ConditionReady, metav1.ConditionFalse, ReasonPaused, installErr.Error())
+	}
+}
+
+// rollback uninstalls the components deployed by the failed attempt in the
+// reverse order they were deployed in. Components which were only upgraded
+// are left as they are.
+func (r *StackReconciler) rollback(ctx context.Context, app *appv1.Stack, installed []stack.ComponentID) ([]string, error) {
+	l := log.FromContext(ctx)
+	kl := logger.NewStructuredLogger(-1, os.Stdout)
+
+	key := r.StateKey(app)
+	appState, ok := r.state.get(key)
+	if !ok || len(installed) == 0 {
+		return nil, nil
+	}
+
+	overrides, err := getStackOverrides(app)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := getStackManifest(kl, app.Spec.StackName, getDesiredOverrides(overrides, appState))
+	if err != nil {
+		return nil, err
+	}
+
+	// Add released the resources when it returned, another Stack could be
+	// installing them by now
+	unlock, err := r.locks.lock(ctx, stackResources(manifest))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	defer func() {
+		r.state.put(key, appState)
+		if err := r.Save(ctx, app); err != nil {
+			l.Error(err, "Failed to save state")
+		}
+	}()
+
+	var rolledBack []string
+	for _, componentId := range slices.Backward(installed) {
+		v, ok := manifest.Components[componentId]
+		if !ok {
+			continue
+		}
+		ver := stacks.GetComponentVersionOverriding(v)
+
+		l.Info("Rolling back", "component", componentId, "stack", app.Spec.StackName)
+		setComponentPhase(app, componentId, v, ver, appv1.ComponentUninstalling, nil)
+		r.componentEvent(app, EventUninstalling, componentId, ver, nil)
+		start := time.Now()
+		if err := r.uninstallComponent(ctx, v); err != nil {
+			setComponentPhase(app, componentId, v, ver, appv1.ComponentFailed, err)
+			r.componentEvent(app, EventUninstallFailed, componentId, ver, err)
+			return rolledBack, err
+		}
+		observeUninstall(app, componentId, start)
+		forgetComponent(app, componentId)
+		delete(appState.Components, string(componentId))
+		setComponentPhase(app, componentId, v, ver, appv1.ComponentUninstalled, nil)
+		r.componentEvent(app, EventUninstalled, componentId, ver, nil)
+		rolledBack = append(rolledBack, string(componentId))
+	}
+	return rolledBack, nil
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+)
+
+var _ = Describe("Stack failure policy", func() {
+	It("should halt paused stacks until their generation changes", func() {
+		r := &StackReconciler{state: &StackState{Stacks: map[string]AppState{}}}
+		app := newStack("spinkube", "wasm/spinkube-standard")
+		app.Generation = 3
+		app.Spec.FailurePolicy = appv1.FailurePause
+
+		Expect(isInstallHalted(app)).To(BeFalse())
+		r.handleInstallFailure(context.Background(), app, nil, errors.New("boom"))
+
+		Expect(app.Status.LastFailure.Action).To(Equal(appv1.FailurePause))
+		Expect(app.Status.LastFailure.Message).To(Equal("boom"))
+		Expect(meta.FindStatusCondition(app.Status.Conditions, appv1.ConditionReady).Reason).To(Equal(ReasonPaused))
+		Expect(isInstallHalted(app)).To(BeTrue())
+
+		app.Generation = 4
+		Expect(isInstallHalted(app)).To(BeFalse())
+	})
+
+	It("should keep retrying by default", func() {
+		r := &StackReconciler{state: &StackState{Stacks: map[string]AppState{}}}
+		app := newStack("spinkube", "wasm/spinkube-standard")
+
+		r.handleInstallFailure(context.Background(), app, nil, errors.New("boom"))
+		Expect(app.Status.LastFailure.Action).To(Equal(appv1.FailureRetry))
+		Expect(isInstallHalted(app)).To(BeFalse())
+	})
+
+	It("should record a rollback with nothing to uninstall", func() {
+		r := &StackReconciler{state: &StackState{Stacks: map[string]AppState{}}}
+		app := newStack("spinkube", "wasm/spinkube-standard")
+		app.Spec.FailurePolicy = appv1.FailureRollback
+
+		r.handleInstallFailure(context.Background(), app, nil, errors.New("boom"))
+		Expect(app.Status.LastFailure.RolledBack).To(BeEmpty())
+		Expect(app.Status.LastFailure.RollbackError).To(BeEmpty())
+		Expect(meta.FindStatusCondition(app.Status.Conditions, appv1.ConditionReady).Reason).To(Equal(ReasonRolledBack))
+		Expect(isInstallHalted(app)).To(BeTrue())
+	})
+
+	It("should wait for the resources of the stack before rolling back", func() {
+		appState := AppState{Components: map[string]ComponentState{"kube-prometheus": {Ver: "72.6.2"}}}
+		r := &StackReconciler{state: &StackState{Stacks: map[string]AppState{"monitoring": appState}}}
+		app := newStack("monitoring", "monitoring-lite")
+
+		manifest, err := getStackManifest(logger.NewStructuredLogger(-1, os.Stdout), "monitoring-lite",
+			getDesiredOverrides(nil, appState))
+		Expect(err).NotTo(HaveOccurred())
+		unlock, err := r.locks.lock(context.Background(), stackResources(manifest))
+		Expect(err).NotTo(HaveOccurred())
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = r.rollback(ctx, app, []stack.ComponentID{"kube-prometheus"})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(app.Status.Components).To(BeEmpty(), "nothing uninstalled without the locks")
+	})
+})
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+// stackResources lists what the stack deploys to that another Stack object
+// could deploy to as well: its helm releases and namespaces.
+func stackResources(manifest stack.ApplicationStack) []string {
+	var resources []string
+	for _, v := range manifest.Components {
+		switch v.HandlerType {
+		case stack.ComponentTypeHelm:
+			if v.Helm == nil {
+				continue
+			}
+			for _, chart := range v.Helm.Charts {
+				resources = append(resources,
+					"release/"+chart.Namespace+"/"+chart.ReleaseName,
+					"namespace/"+chart.Namespace)
+			}
+		case stack.ComponentTypeKubectl:
+			if v.Kubectl != nil && len(v.Kubectl.Namespace) != 0 {
+				resources = append(resources, "namespace/"+v.Kubectl.Namespace)
+			}
+		}
+	}
+	slices.Sort(resources)
+	return slices.Compact(resources)
+}
+
+// resourceLocks serializes the workers of Stack objects deploying to the same
+// resources, the ones deploying elsewhere go on in parallel. A worker gets all
+// of its resources at once so two of them never wait on each other.
+type resourceLocks struct {
+	mu   sync.Mutex
+	held map[string]chan struct{}
+}
+
+// lock blocks until none of the resources is held by another worker, the
+// returned func releases them.
+func (l *resourceLocks) lock(ctx context.Context, resources []string) (func(), error) {
+	for {
+		l.mu.Lock()
+		if l.held == nil {
+			l.held = map[string]chan struct{}{}
+		}
+		var busy chan struct{}
+		for _, res := range resources {
+			if ch, ok := l.held[res]; ok {
+				busy = ch
+				break
+			}
+		}
+		if busy == nil {
+			release := make(chan struct{})
+			for _, res := range resources {
+				l.held[res] = release
+			}
+			l.mu.Unlock()
+
+			return func() {
+				l.mu.Lock()
+				defer l.mu.Unlock()
+				for _, res := range resources {
+					delete(l.held, res)
+				}
+				close(release)
+			}, nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-busy:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stack resource locks", func() {
+	It("should list the helm releases and namespaces of a stack", func() {
+		Expect(stackResources(monitoringManifest("monitoring"))).To(Equal([]string{
+			"namespace/monitoring",
+			"release/monitoring/kube-prometheus-stack",
+		}))
+		Expect(stackResources(gitopsManifest("v2.14.2", "2.39.1"))).To(Equal([]string{
+			"namespace/argo-rollouts",
+			"namespace/argocd",
+			"release/argo-rollouts/argo-rollouts",
+		}))
+	})
+
+	It("should serialize stacks sharing resources only", func() {
+		ctx := context.Background()
+		var locks resourceLocks
+
+		unlock, err := locks.lock(ctx, stackResources(monitoringManifest("monitoring")))
+		Expect(err).NotTo(HaveOccurred())
+
+		unrelated, err := locks.lock(ctx, stackResources(gitopsManifest("v2.14.2", "2.39.1")))
+		Expect(err).NotTo(HaveOccurred())
+		unrelated()
+
+		acquired := make(chan func())
+		go func() {
+			defer GinkgoRecover()
+			unlockOther, err := locks.lock(ctx, []string{"namespace/monitoring"})
+			Expect(err).NotTo(HaveOccurred())
+			acquired <- unlockOther
+		}()
+		Consistently(acquired, 100*time.Millisecond).ShouldNot(Receive())
+
+		unlock()
+		var unlockOther func()
+		Eventually(acquired).Should(Receive(&unlockOther))
+		unlockOther()
+	})
+
+	It("should give up waiting with the context", func() {
+		var locks resourceLocks
+		unlock, err := locks.lock(context.Background(), []string{"namespace/monitoring"})
+		Expect(err).NotTo(HaveOccurred())
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = locks.lock(ctx, []string{"namespace/monitoring"})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should only hand out copies of the state", func() {
+		state := &StackState{Stacks: map[string]AppState{
+			"monitoring-a": {Components: map[string]ComponentState{"kube-prometheus": {Ver: "72.6.2"}}},
+		}}
+
+		appState, ok := state.get("monitoring-a")
+		Expect(ok).To(BeTrue())
+		delete(appState.Components, "kube-prometheus")
+		Expect(state.Stacks["monitoring-a"].Components).To(HaveKey("kube-prometheus"))
+
+		state.put("monitoring-a", appState)
+		Expect(state.Stacks["monitoring-a"].Components).To(BeEmpty())
+	})
+})
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+const (
+	OperationInstall   string = "install"
+	OperationUninstall string = "uninstall"
+	OperationPlan      string = "plan"
+)
+
+var (
+	componentInstallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ka_component_install_duration_seconds",
+		Help:    "Time taken to install or upgrade a component until it became ready.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"stack", "component"})
+
+	componentUninstallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ka_component_uninstall_duration_seconds",
+		Help:    "Time taken to uninstall a component.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"stack", "component"})
+
+	stackFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ka_stack_failures_total",
+		Help: "Failed attempts to reconcile a stack by operation and class of error.",
+	}, []string{"stack", "operation", "error_class"})
+
+	componentInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ka_component_info",
+		Help: "Version of every installed component, always 1.",
+	}, []string{"stack", "component", "version"})
+
+	stackDriftedComponents = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ka_stack_drifted_components",
+		Help: "Number of installed components of the stack which drifted from what was deployed.",
+	}, []string{"stack"})
+
+	stackPendingApproval = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ka_stack_pending_approval",
+		Help: "Whether the plan of the stack waits for approval.",
+	}, []string{"stack"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		componentInstallDuration,
+		componentUninstallDuration,
+		stackFailures,
+		componentInfo,
+		stackDriftedComponents,
+		stackPendingApproval,
+	)
+}
+
+// errorClass groups the errors of failed attempts so alerts don't depend on
+// their messages.
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, ErrStackConflict):
+		return "conflict"
+	case errors.Is(err, ErrComponentNotReady):
+		return "not_ready"
+	case errors.Is(err, ErrPlanOutdated):
+		return "plan_outdated"
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return "timeout"
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return "forbidden"
+	case apierrors.IsNotFound(err):
+		return "not_found"
+	case apierrors.IsConflict(err):
+		return "api_conflict"
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return "invalid"
+	}
+	return "other"
+}
+
+func observeInstall(app *appv1.Stack, componentId stack.ComponentID, start time.Time) {
+	componentInstallDuration.WithLabelValues(app.Name, string(componentId)).Observe(time.Since(start).Seconds())
+}
+
+func observeUninstall(app *appv1.Stack, componentId stack.ComponentID, start time.Time) {
+	componentUninstallDuration.WithLabelValues(app.Name, string(componentId)).Observe(time.Since(start).Seconds())
+}
+
+func recordStackFailure(app *appv1.Stack, operation string, err error) {
+	stackFailures.WithLabelValues(app.Name, operation, errorClass(err)).Inc()
+}
+
+// recordComponentVersion replaces the version the component was reported with.
+func recordComponentVersion(app *appv1.Stack, componentId stack.ComponentID, ver string) {
+	forgetComponent(app, componentId)
+	componentInfo.WithLabelValues(app.Name, string(componentId), ver).Set(1)
+}
+
+func forgetComponent(app *appv1.Stack, componentId stack.ComponentID) {
+	componentInfo.DeletePartialMatch(prometheus.Labels{"stack": app.Name, "component": string(componentId)})
+}
+
+// recordStackStatus reflects the drift and the approval in the status of the
+// stack in the gauges.
+func recordStackStatus(app *appv1.Stack) {
+	var drifted int
+	for _, c := range app.Status.Components {
+		if c.Phase == appv1.ComponentDrifted {
+			drifted++
+		}
+	}
+	stackDriftedComponents.WithLabelValues(app.Name).Set(float64(drifted))
+
+	var pending float64
+	if meta.IsStatusConditionTrue(app.Status.Conditions, appv1.ConditionPendingApproval) {
+		pending = 1
+	}
+	stackPendingApproval.WithLabelValues(app.Name).Set(pending)
+}
+
+// forgetStack drops every series of a deleted stack.
+func forgetStack(app *appv1.Stack) {
+	labels := prometheus.Labels{"stack": app.Name}
+	componentInstallDuration.DeletePartialMatch(labels)
+	componentUninstallDuration.DeletePartialMatch(labels)
+	stackFailures.DeletePartialMatch(labels)
+	componentInfo.DeletePartialMatch(labels)
+	stackDriftedComponents.DeletePartialMatch(labels)
+	stackPendingApproval.DeletePartialMatch(labels)
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+var _ = Describe("Stack metrics", func() {
+	It("should classify errors of failed attempts", func() {
+		Expect(errorClass(fmt.Errorf("%w: monitoring/kube-prometheus-stack", ErrStackConflict))).To(Equal("conflict"))
+		Expect(errorClass(fmt.Errorf("%w: argocd", ErrComponentNotReady))).To(Equal("not_ready"))
+		Expect(errorClass(errors.New("boom"))).To(Equal("other"))
+	})
+
+	It("should keep a single version per installed component", func() {
+		app := newStack("metrics-info", "gitops-standard")
+		recordComponentVersion(app, "argocd", "v2.14.2")
+		recordComponentVersion(app, "argocd", "v2.14.3")
+
+		Expect(testutil.ToFloat64(componentInfo.WithLabelValues("metrics-info", "argocd", "v2.14.3"))).To(Equal(1.0))
+		Expect(testutil.CollectAndCount(componentInfo)).To(Equal(1))
+
+		forgetStack(app)
+		Expect(testutil.CollectAndCount(componentInfo)).To(Equal(0))
+	})
+
+	It("should report drifted components and pending approvals", func() {
+		app := newStack("metrics-status", "gitops-standard")
+		app.Status.Components = []appv1.ComponentStatus{
+			{ID: "argocd", Phase: appv1.ComponentDrifted},
+			{ID: "argo-rollouts", Phase: appv1.ComponentInstalled},
+		}
+		setStackCondition(app, appv1.ConditionPendingApproval, metav1.ConditionTrue, ReasonAwaitingApproval, "")
+		recordStackStatus(app)
+
+		Expect(testutil.ToFloat64(stackDriftedComponents.WithLabelValues("metrics-status"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(stackPendingApproval.WithLabelValues("metrics-status"))).To(Equal(1.0))
+		forgetStack(app)
+	})
+
+	It("should count failures by class", func() {
+		app := newStack("metrics-failures", "gitops-standard")
+		recordStackFailure(app, OperationInstall, fmt.Errorf("%w: argocd", ErrComponentNotReady))
+		observeInstall(app, "argocd", time.Now())
+
+		Expect(testutil.ToFloat64(stackFailures.WithLabelValues("metrics-failures", OperationInstall, "not_ready"))).To(Equal(1.0))
+		forgetStack(app)
+	})
+})
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+const DefaultMaxParallelComponents int = 4
+
+func (r *StackReconciler) maxParallelComponents() int {
+	if r.MaxParallelComponents <= 0 {
+		return DefaultMaxParallelComponents
+	}
+	return r.MaxParallelComponents
+}
+
+// stepGuard serializes the changes the components installed side by side make
+// to the Stack status and the state, the handlers run outside of it.
+type stepGuard struct {
+	mu sync.Mutex
+}
+
+func (g *stepGuard) lock() {
+	if g != nil {
+		g.mu.Lock()
+	}
+}
+
+func (g *stepGuard) unlock() {
+	if g != nil {
+		g.mu.Unlock()
+	}
+}
+
+// outside runs fn with the guard released, it has to be held by the caller.
+func (g *stepGuard) outside(fn func() error) error {
+	g.unlock()
+	defer g.lock()
+	return fn()
+}
+
+// inParallel runs fn for every component at once, at most
+// MaxParallelComponents at a time, and waits for all of them to finish. A
+// failing component doesn't stop the others, their handlers can't be
+// interrupted safely anyway. Only their readiness is awaited side by side for
+// helm components, the ksctl helm client deploys one at a time.
+func (r *StackReconciler) inParallel(componentIds []stack.ComponentID, fn func(stack.ComponentID) error) error {
+	if len(componentIds) == 1 {
+		return fn(componentIds[0])
+	}
+
+	sem := make(chan struct{}, r.maxParallelComponents())
+	errs := make([]error, len(componentIds))
+	var wg sync.WaitGroup
+	for i, componentId := range componentIds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = fn(componentId)
+		}()
+	}
+	wg.Wait()
+
+	errs = slices.DeleteFunc(errs, func(err error) bool { return err == nil })
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// stepGroups splits the steps of the plan into the groups which run one after
+// the other: uninstalls by the levels of the dependency graph in reverse, then
+// the other steps level by level. The steps of a group run side by side.
+func stepGroups(steps []appv1.PlanStep, levels [][]stack.ComponentID) [][]appv1.PlanStep {
+	levelOf := make(map[string]int)
+	for i, level := range levels {
+		for _, componentId := range level {
+			levelOf[string(componentId)] = i
+		}
+	}
+
+	uninstalls := make([][]appv1.PlanStep, len(levels)+1)
+	others := make([][]appv1.PlanStep, len(levels)+1)
+	for _, step := range steps {
+		level := levelOf[step.ID]
+		if step.Action == appv1.PlanUninstall {
+			uninstalls[level] = append(uninstalls[level], step)
+		} else {
+			others[level] = append(others[level], step)
+		}
+	}
+
+	var groups [][]appv1.PlanStep
+	for _, group := range slices.Backward(uninstalls) {
+		if len(group) != 0 {
+			groups = append(groups, group)
+		}
+	}
+	for _, group := range others {
+		if len(group) != 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parallel component installation", func() {
+	It("should group the steps by the levels of the dependency graph", func() {
+		levels := [][]stack.ComponentID{{"a", "b"}, {"c"}}
+		steps := []appv1.PlanStep{
+			{ID: "c", Action: appv1.PlanUninstall},
+			{ID: "b", Action: appv1.PlanUninstall},
+			{ID: "a", Action: appv1.PlanInstall},
+			{ID: "b", Action: appv1.PlanSkip},
+			{ID: "c", Action: appv1.PlanUpgrade},
+		}
+
+		groups := stepGroups(steps, levels)
+		ids := make([][]string, 0, len(groups))
+		for _, group := range groups {
+			var g []string
+			for _, step := range group {
+				g = append(g, step.ID)
+			}
+			ids = append(ids, g)
+		}
+		Expect(ids).To(Equal([][]string{{"c"}, {"b"}, {"a", "b"}, {"c"}}))
+	})
+
+	It("should bound the components running at once and collect their errors", func() {
+		r := &StackReconciler{MaxParallelComponents: 2}
+		errA, errB := errors.New("a failed"), errors.New("b failed")
+
+		var running, peak atomic.Int32
+		err := r.inParallel([]stack.ComponentID{"a", "b", "c", "d", "e"}, func(componentId stack.ComponentID) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			switch componentId {
+			case "a":
+				return errA
+			case "b":
+				return errB
+			}
+			return nil
+		})
+		Expect(peak.Load()).To(BeNumerically("==", 2))
+		Expect(err).To(MatchError(errA))
+		Expect(err).To(MatchError(errB))
+
+		Expect(r.inParallel([]stack.ComponentID{"a"}, func(stack.ComponentID) error { return errA })).To(Equal(errA))
+	})
+})
@@ -0,0 +1,326 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	ksctlErrors "github.com/ksctl/ksctl/v2/pkg/errors"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+)
+
+var ErrPlanOutdated = errors.New("stack no longer matches its plan")
+
+func getStackMode(app *appv1.Stack) appv1.StackMode {
+	if len(app.Spec.Mode) == 0 {
+		return appv1.ModeApply
+	}
+	return app.Spec.Mode
+}
+
+// specHash identifies the spec a plan gets computed for, the mode is left
+// out so switching from plan to apply keeps the plan.
+func specHash(app *appv1.Stack) (string, error) {
+	spec := app.Spec.DeepCopy()
+	spec.Mode = ""
+	return hashJSON(spec)
+}
+
+// planHash covers the spec along with the steps, a plan with the same steps
+// computed for a changed spec needs to be approved again.
+func planHash(specHash string, steps []appv1.PlanStep) (string, error) {
+	return hashJSON(struct {
+		SpecHash string           `json:"specHash"`
+		Steps    []appv1.PlanStep `json:"steps"`
+	}{specHash, steps})
+}
+
+func newStackPlan(app *appv1.Stack, steps []appv1.PlanStep) (*appv1.StackPlan, error) {
+	spec, err := specHash(app)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := planHash(spec, steps)
+	if err != nil {
+		return nil, err
+	}
+	return &appv1.StackPlan{
+		Hash:          hash,
+		SpecHash:      spec,
+		Steps:         steps,
+		GeneratedTime: metav1.Now(),
+	}, nil
+}
+
+// hasChanges tells whether any step of the plan changes the cluster.
+func hasChanges(steps []appv1.PlanStep) bool {
+	return slices.ContainsFunc(steps, func(step appv1.PlanStep) bool {
+		return step.Action != appv1.PlanSkip
+	})
+}
+
+func hashJSON(v any) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// appliedPlan returns the plan apply has to stick to, nothing when the plan
+// was computed for another spec.
+func appliedPlan(app *appv1.Stack) (*appv1.StackPlan, error) {
+	plan := app.Status.Plan
+	if plan == nil || getStackMode(app) != appv1.ModeApply {
+		return nil, nil
+	}
+	hash, err := specHash(app)
+	if err != nil {
+		return nil, err
+	}
+	if plan.SpecHash != hash {
+		return nil, nil
+	}
+	return plan, nil
+}
+
+// pinPlannedVersions renders the stack with the versions resolved when the
+// plan was computed, so a new upstream release doesn't sneak into the apply.
+func pinPlannedVersions(overrides map[string]map[string]any, plan *appv1.StackPlan) {
+	for _, step := range plan.Steps {
+		if step.Action != appv1.PlanInstall && step.Action != appv1.PlanUpgrade {
+			continue
+		}
+		if len(step.Version) == 0 {
+			continue
+		}
+		if overrides[step.ID] == nil {
+			overrides[step.ID] = map[string]any{}
+		}
+		overrides[step.ID]["version"] = step.Version
+	}
+}
+
+// checkPlan makes sure applying carries out nothing but the planned steps.
+// Steps already carried out by an earlier attempt show up as skipped.
+func checkPlan(planned, steps []appv1.PlanStep) error {
+	byID := make(map[string]appv1.PlanStep, len(planned))
+	for _, step := range planned {
+		byID[step.ID] = step
+	}
+	for _, step := range steps {
+		if step.Action == appv1.PlanSkip {
+			continue
+		}
+		p, ok := byID[step.ID]
+		if !ok || p.Action != step.Action || p.Version != step.Version {
+			return fmt.Errorf("%w: %s %s %s was not planned", ErrPlanOutdated, strings.ToLower(string(step.Action)), step.ID, step.Version)
+		}
+	}
+	return nil
+}
+
+func newPlanStep(
+	componentId stack.ComponentID,
+	v stack.Component,
+	action appv1.PlanAction,
+	ver string,
+	installedVer string,
+	reason string,
+) appv1.PlanStep {
+	step := appv1.PlanStep{
+		ID:               string(componentId),
+		Action:           action,
+		HandlerType:      string(v.HandlerType),
+		Reason:           reason,
+		Version:          ver,
+		InstalledVersion: installedVer,
+	}
+	if v.HandlerType == stack.ComponentTypeKubectl && v.Kubectl != nil {
+		step.URLs = slices.Clone(v.Kubectl.Urls)
+		step.Namespace = v.Kubectl.Namespace
+	} else if v.Helm != nil {
+		for _, chart := range v.Helm.Charts {
+			repo := v.Helm.RepoUrl
+			if len(chart.ChartRef) != 0 {
+				repo = chart.ChartRef
+			}
+			step.Charts = append(step.Charts, appv1.PlannedChart{
+				Name:        chart.Name,
+				Version:     chart.Version,
+				ReleaseName: chart.ReleaseName,
+				Namespace:   chart.Namespace,
+				Repository:  repo,
+			})
+		}
+	}
+	return step
+}
+
+// buildPlan lists what applying the stack does: disabled components get
+// uninstalled first with dependents going first, then every component gets
+// installed, upgraded or skipped level by level of the dependency graph.
+func buildPlan(app *appv1.Stack, manifest stack.ApplicationStack, appState AppState) ([]appv1.PlanStep, error) {
+	kl := logger.NewStructuredLogger(-1, os.Stdout)
+
+	levels, err := stacks.GetInstallLevels(app.Spec.StackName, manifest)
+	if err != nil {
+		return nil, err
+	}
+	order := slices.Concat(levels...)
+
+	var steps []appv1.PlanStep
+	for _, componentId := range slices.Backward(order) {
+		componentState, installed := appState.Components[string(componentId)]
+		if !installed || !slices.Contains(app.Spec.DisableComponents, string(componentId)) {
+			continue
+		}
+		v, ok := manifest.Components[componentId]
+		if !ok {
+			continue
+		}
+		steps = append(steps, newPlanStep(componentId, v, appv1.PlanUninstall, componentState.Ver, componentState.Ver, "component is disabled"))
+	}
+
+	for _, componentId := range order {
+		disabled := slices.Contains(app.Spec.DisableComponents, string(componentId))
+		componentState, installed := appState.Components[string(componentId)]
+		v, ok := manifest.Components[componentId]
+
+		switch {
+		case installed && disabled:
+			// covered by the uninstall steps
+			continue
+
+		case installed && !ok:
+			steps = append(steps, appv1.PlanStep{
+				ID:               string(componentId),
+				Action:           appv1.PlanSkip,
+				InstalledVersion: componentState.Ver,
+				Reason:           "not part of the stack",
+			})
+
+		case installed:
+			ver := stacks.GetComponentVersionOverriding(v)
+			hash, err := stacks.GetComponentHash(v)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case componentState.Ver != ver:
+				steps = append(steps, newPlanStep(componentId, v, appv1.PlanUpgrade, ver, componentState.Ver,
+					fmt.Sprintf("version changes from %s to %s", componentState.Ver, ver)))
+			case componentState.Hash != "" && componentState.Hash != hash:
+				steps = append(steps, newPlanStep(componentId, v, appv1.PlanUpgrade, ver, componentState.Ver, "configuration changed"))
+			default:
+				steps = append(steps, newPlanStep(componentId, v, appv1.PlanSkip, ver, componentState.Ver, "up to date"))
+			}
+
+		case disabled:
+			if ok {
+				steps = append(steps, newPlanStep(componentId, v, appv1.PlanSkip, stacks.GetComponentVersionOverriding(v), "", "component is disabled"))
+			}
+
+		case !ok:
+			return nil, ksctlErrors.WrapError(
+				ksctlErrors.ErrFailedKsctlComponent,
+				kl.NewError(context.Background(), "component not found", "componentId", componentId),
+			)
+
+		default:
+			steps = append(steps, newPlanStep(componentId, v, appv1.PlanInstall, stacks.GetComponentVersionOverriding(v), "", "not installed"))
+		}
+	}
+	return steps, nil
+}
+
+// planStack renders the stack the way applying it would and plans the steps,
+// with a plan given its versions are kept.
+func planStack(
+	app *appv1.Stack,
+	appState AppState,
+	plan *appv1.StackPlan,
+) (stack.ApplicationStack, []appv1.PlanStep, error) {
+	kl := logger.NewStructuredLogger(-1, os.Stdout)
+
+	overrides, err := getStackOverrides(app)
+	if err != nil {
+		return stack.ApplicationStack{}, nil, err
+	}
+	desired := getDesiredOverrides(overrides, appState)
+	due, err := dueUpdates(app, overrides, appState, time.Now())
+	if err != nil {
+		return stack.ApplicationStack{}, nil, err
+	}
+	for id, ver := range due {
+		desired[id]["version"] = ver
+	}
+	if plan != nil {
+		pinPlannedVersions(desired, plan)
+	}
+	manifest, err := getStackManifest(kl, app.Spec.StackName, desired)
+	if err != nil {
+		return stack.ApplicationStack{}, nil, err
+	}
+	steps, err := buildPlan(app, manifest, appState)
+	if err != nil {
+		return stack.ApplicationStack{}, nil, err
+	}
+	return manifest, steps, nil
+}
+
+// Plan writes what applying the stack would do into the status without
+// touching the cluster. A plan already computed for the spec is kept.
+func (r *StackReconciler) Plan(ctx context.Context, app *appv1.Stack) error {
+	hash, err := specHash(app)
+	if err != nil {
+		return err
+	}
+	if app.Status.Plan != nil && app.Status.Plan.SpecHash == hash {
+		return nil
+	}
+
+	key := r.StateKey(app)
+	appState, ok := r.state.get(key)
+	if !ok {
+		appState = AppState{Components: map[string]ComponentState{}}
+	}
+
+	manifest, steps, err := planStack(app, appState, nil)
+	if err != nil {
+		return err
+	}
+	if err := findConflict(r.state, key, manifest, app.Spec.DisableComponents); err != nil {
+		return err
+	}
+
+	plan, err := newStackPlan(app, steps)
+	if err != nil {
+		return err
+	}
+	app.Status.Plan = plan
+	return nil
+}
+
+// planSummary counts the steps of the plan which change something.
+func planSummary(plan *appv1.StackPlan) string {
+	counts := map[appv1.PlanAction]int{}
+	for _, step := range plan.Steps {
+		counts[step.Action]++
+	}
+	return fmt.Sprintf("%d to install, %d to upgrade, %d to uninstall",
+		counts[appv1.PlanInstall], counts[appv1.PlanUpgrade], counts[appv1.PlanUninstall])
+}
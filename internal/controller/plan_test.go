@@ -0,0 +1,134 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/ksctl/ksctl/v2/pkg/k8s"
+)
+
+func gitopsManifest(argocdVer, rolloutsVer string) stack.ApplicationStack {
+	return stack.ApplicationStack{
+		StkDepsIdx: []stack.ComponentID{"argocd", "argo-rollouts"},
+		Components: map[stack.ComponentID]stack.Component{
+			"argocd": {
+				HandlerType: stack.ComponentTypeKubectl,
+				Kubectl: &k8s.App{
+					Namespace: "argocd",
+					Urls:      []string{"https://example.com/argocd/" + argocdVer + "/install.yaml"},
+					Version:   argocdVer,
+				},
+			},
+			"argo-rollouts": {
+				HandlerType: stack.ComponentTypeHelm,
+				Helm: &helm.App{
+					RepoUrl: "https://argoproj.github.io/argo-helm",
+					Charts: []helm.ChartOptions{{
+						Name:        "argo/argo-rollouts",
+						Version:     rolloutsVer,
+						ReleaseName: "argo-rollouts",
+						Namespace:   "argo-rollouts",
+					}},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Stack plans", func() {
+	It("should plan installing every enabled component in order", func() {
+		app := newStack("gitops", "gitops-standard")
+		steps, err := buildPlan(app, gitopsManifest("v2.14.2", "2.39.1"), AppState{Components: map[string]ComponentState{}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(steps).To(HaveLen(2))
+		Expect(steps[0].ID).To(Equal("argocd"))
+		Expect(steps[0].Action).To(Equal(appv1.PlanInstall))
+		Expect(steps[0].URLs).To(Equal([]string{"https://example.com/argocd/v2.14.2/install.yaml"}))
+		Expect(steps[0].Namespace).To(Equal("argocd"))
+		Expect(steps[1].Action).To(Equal(appv1.PlanInstall))
+		Expect(steps[1].Charts).To(Equal([]appv1.PlannedChart{{
+			Name:        "argo/argo-rollouts",
+			Version:     "2.39.1",
+			ReleaseName: "argo-rollouts",
+			Namespace:   "argo-rollouts",
+			Repository:  "https://argoproj.github.io/argo-helm",
+		}}))
+	})
+
+	It("should plan upgrades, skips and uninstalls of installed components", func() {
+		installed := gitopsManifest("v2.14.2", "2.39.1")
+		hash, err := stacks.GetComponentHash(installed.Components["argocd"])
+		Expect(err).NotTo(HaveOccurred())
+		appState := AppState{Components: map[string]ComponentState{
+			"argocd":        newComponentState(installed.Components["argocd"], "v2.14.2", hash),
+			"argo-rollouts": newComponentState(installed.Components["argo-rollouts"], "2.39.1", ""),
+		}}
+
+		app := newStack("gitops", "gitops-standard")
+		steps, err := buildPlan(app, gitopsManifest("v2.14.2", "2.39.1"), appState)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(steps).To(HaveLen(2))
+		Expect(steps[0].Action).To(Equal(appv1.PlanSkip))
+		Expect(steps[1].Action).To(Equal(appv1.PlanSkip))
+
+		steps, err = buildPlan(app, gitopsManifest("v2.14.3", "2.39.1"), appState)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(steps[0].Action).To(Equal(appv1.PlanUpgrade))
+		Expect(steps[0].Version).To(Equal("v2.14.3"))
+		Expect(steps[0].InstalledVersion).To(Equal("v2.14.2"))
+
+		app.Spec.DisableComponents = []string{"argo-rollouts"}
+		steps, err = buildPlan(app, gitopsManifest("v2.14.2", "2.39.1"), appState)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(steps).To(HaveLen(2))
+		Expect(steps[0].ID).To(Equal("argo-rollouts"))
+		Expect(steps[0].Action).To(Equal(appv1.PlanUninstall))
+		Expect(steps[1].ID).To(Equal("argocd"))
+	})
+
+	It("should only apply a plan computed for the current spec", func() {
+		app := newStack("gitops", "gitops-standard")
+		app.Spec.Mode = appv1.ModePlan
+		hash, err := specHash(app)
+		Expect(err).NotTo(HaveOccurred())
+		app.Status.Plan = &appv1.StackPlan{SpecHash: hash}
+
+		Expect(appliedPlan(app)).To(BeNil())
+
+		app.Spec.Mode = appv1.ModeApply
+		Expect(appliedPlan(app)).To(Equal(app.Status.Plan))
+
+		app.Spec.DisableComponents = []string{"argo-rollouts"}
+		Expect(appliedPlan(app)).To(BeNil())
+	})
+
+	It("should reject steps which were not planned", func() {
+		planned := []appv1.PlanStep{
+			{ID: "argocd", Action: appv1.PlanUpgrade, Version: "v2.14.3"},
+			{ID: "argo-rollouts", Action: appv1.PlanInstall, Version: "2.39.1"},
+		}
+
+		Expect(checkPlan(planned, planned)).To(Succeed())
+		Expect(checkPlan(planned, []appv1.PlanStep{
+			{ID: "argocd", Action: appv1.PlanSkip, Version: "v2.14.3"},
+			{ID: "argo-rollouts", Action: appv1.PlanInstall, Version: "2.39.1"},
+		})).To(Succeed())
+		Expect(checkPlan(planned, []appv1.PlanStep{
+			{ID: "argocd", Action: appv1.PlanUpgrade, Version: "v2.14.4"},
+		})).To(MatchError(ErrPlanOutdated))
+	})
+
+	It("should pin the versions resolved by the plan", func() {
+		overrides := map[string]map[string]any{"argocd": {"version": "latest"}}
+		pinPlannedVersions(overrides, &appv1.StackPlan{Steps: []appv1.PlanStep{
+			{ID: "argocd", Action: appv1.PlanInstall, Version: "v2.14.3"},
+			{ID: "argo-rollouts", Action: appv1.PlanSkip, Version: "2.39.1"},
+		}})
+		Expect(overrides).To(Equal(map[string]map[string]any{"argocd": {"version": "v2.14.3"}}))
+	})
+})
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/executor"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+const (
+	DefaultReadinessTimeout time.Duration = 5 * time.Minute
+
+	readinessPollInterval time.Duration = 5 * time.Second
+)
+
+var ErrComponentNotReady = errors.New("component not ready")
+
+func (r *StackReconciler) readinessTimeout() time.Duration {
+	if r.ReadinessTimeout == 0 {
+		return DefaultReadinessTimeout
+	}
+	return r.ReadinessTimeout
+}
+
+func (r *StackReconciler) readinessCheck(ctx context.Context, v stack.Component) (executor.ReadinessCheck, error) {
+	if v.HandlerType == stack.ComponentTypeKubectl {
+		return executor.K8sReadinessCheck(ctx, r.RestConfig, v.Kubectl)
+	}
+	return executor.HelmReadinessCheck(ctx, r.RestConfig, v.Helm)
+}
+
+// awaitComponent waits for a deployed component to become ready, the state is
+// recorded beforehand so a retry after a timeout waits again instead of
+// deploying the component a second time. The guard, if any, is released while
+// waiting.
+func (r *StackReconciler) awaitComponent(
+	ctx context.Context,
+	g *stepGuard,
+	app *appv1.Stack,
+	appState AppState,
+	componentId stack.ComponentID,
+	v stack.Component,
+	ver string,
+	componentState ComponentState,
+) error {
+	componentState.WaitingReady = true
+	appState.Components[string(componentId)] = componentState
+
+	if err := g.outside(func() error { return r.waitForComponent(ctx, componentId, v) }); err != nil {
+		setComponentPhase(app, componentId, v, ver, appv1.ComponentFailed, err)
+		return err
+	}
+
+	componentState.WaitingReady = false
+	appState.Components[string(componentId)] = componentState
+	return nil
+}
+
+// waitForComponent blocks until the component deployed last is ready, so the
+// next one in the dependency index never starts against a half running one
+// (e.g. a chart shipping resources validated by the cert-manager webhook).
+func (r *StackReconciler) waitForComponent(ctx context.Context, componentId stack.ComponentID, v stack.Component) error {
+	l := log.FromContext(ctx)
+
+	check, err := r.readinessCheck(ctx, v)
+	if err != nil {
+		return err
+	}
+
+	var notReady []string
+	var lastErr error
+	err = wait.PollUntilContextTimeout(ctx, readinessPollInterval, r.readinessTimeout(), true,
+		func(ctx context.Context) (bool, error) {
+			notReady, lastErr = check(ctx)
+			if lastErr != nil {
+				// the objects might not be there yet, keep polling until the timeout
+				l.V(1).Info("Readiness check failed", "component", componentId, "reason", lastErr.Error())
+				return false, nil
+			}
+			return len(notReady) == 0, nil
+		})
+	if err == nil {
+		l.Info("Component ready", "component", componentId)
+		return nil
+	}
+	if !wait.Interrupted(err) {
+		return err
+	}
+
+	reason := strings.Join(notReady, "; ")
+	if lastErr != nil {
+		reason = lastErr.Error()
+	}
+	return fmt.Errorf("%w: %s after %s: %s", ErrComponentNotReady, componentId, r.readinessTimeout(), reason)
+}
@@ -3,184 +3,554 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"maps"
 	"os"
 	"slices"
+	"strings"
+	"time"
 
 	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ka/internal/executor"
 	"github.com/ksctl/ka/internal/stacks"
 	"github.com/ksctl/ka/internal/stacks/wasm"
+	"github.com/ksctl/ka/internal/tracing"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 	ksctlErrors "github.com/ksctl/ksctl/v2/pkg/errors"
 	"github.com/ksctl/ksctl/v2/pkg/logger"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+func getStackOverrides(app *appv1.Stack) (map[string]map[string]any, error) {
+	if app.Spec.Overrides == nil {
+		return nil, nil
+	}
+	_overrides := make(map[string]map[string]any)
+	if err := json.Unmarshal(app.Spec.Overrides.Raw, &_overrides); err != nil {
+		return nil, err
+	}
+	return _overrides, nil
+}
+
 func GetStackManifest(kl logger.Logger, app *appv1.Stack) (stack.ApplicationStack, error) {
+	_overrides, err := getStackOverrides(app)
+	if err != nil {
+		return stack.ApplicationStack{}, err
+	}
+	return getStackManifest(kl, app.Spec.StackName, _overrides)
+}
+
+func getStackManifest(kl logger.Logger, stackName string, overrides map[string]map[string]any) (stack.ApplicationStack, error) {
 
-	appStk, err := stacks.Get(context.Background(), kl, app.Spec.StackName)
+	appStk, err := stacks.Get(context.Background(), kl, stackName)
 	if err != nil {
 		return stack.ApplicationStack{}, err
 	}
 	convertedOverriding := make(map[stack.ComponentID]stack.ComponentOverrides)
 
-	var _overrides map[string]map[string]any = nil
-	if app.Spec.Overrides != nil {
-		_overrides = make(map[string]map[string]any)
-		if err := json.Unmarshal(app.Spec.Overrides.Raw, &_overrides); err != nil {
-			return stack.ApplicationStack{}, err
-		}
+	for k, v := range overrides {
+		convertedOverriding[stack.ComponentID(k)] = stack.ComponentOverrides(maps.Clone(v))
+	}
+	return appStk(stack.ApplicationParams{ComponentParams: convertedOverriding})
+}
 
-		for k, v := range _overrides {
-			convertedOverriding[stack.ComponentID(k)] = stack.ComponentOverrides(v)
+// getDesiredOverrides returns the overrides the stack gets rendered with: installed
+// components which don't pin a version, or run one still satisfying their version
+// constraint, are kept on the version they run, so only a change made to the Stack
+// can cause them to be re-deployed.
+func getDesiredOverrides(overrides map[string]map[string]any, appState AppState) map[string]map[string]any {
+	desired := make(map[string]map[string]any, len(overrides))
+	for k, v := range overrides {
+		desired[k] = maps.Clone(v)
+	}
+	for componentId, componentState := range appState.Components {
+		if isVersionPinned(overrides, stack.ComponentID(componentId)) {
+			constraint := versionConstraint(overrides, stack.ComponentID(componentId))
+			if len(constraint) == 0 || !apps.SatisfiesVersion(constraint, componentState.Ver) {
+				continue
+			}
 		}
+		if desired[componentId] == nil {
+			desired[componentId] = map[string]any{}
+		}
+		desired[componentId]["version"] = componentState.Ver
 	}
-	return appStk(stack.ApplicationParams{ComponentParams: convertedOverriding})
+	return desired
+}
+
+// isVersionPinned tells whether the overrides explicitly ask for a version of
+// the component, components following "latest" are never upgraded implicitly
+// as that would roll out every upstream release on the next reconcile.
+func isVersionPinned(overrides map[string]map[string]any, componentId stack.ComponentID) bool {
+	v, ok := overrides[string(componentId)]["version"].(string)
+	return ok && v != "latest"
+}
+
+// versionConstraint returns the semver constraint the overrides resolve the
+// version of the component with, if any.
+func versionConstraint(overrides map[string]map[string]any, componentId stack.ComponentID) string {
+	v, _ := overrides[string(componentId)]["version"].(string)
+	if !apps.IsVersionConstraint(v) {
+		return ""
+	}
+	return v
+}
+
+// componentContext carries the stack and the component into the spans of the
+// handlers deploying it.
+func componentContext(ctx context.Context, app *appv1.Stack, componentId stack.ComponentID, ver string) context.Context {
+	return tracing.ContextWithAttributes(ctx,
+		tracing.AttrStackID.String(app.Spec.StackName),
+		tracing.AttrComponent.String(string(componentId)),
+		tracing.AttrVersion.String(ver),
+	)
+}
+
+func (r *StackReconciler) installComponent(ctx context.Context, v stack.Component) error {
+	if v.HandlerType == stack.ComponentTypeKubectl {
+		return executor.K8sDeployHandler(ctx, r.RestConfig, v.Kubectl)
+	}
+	return executor.HelmDeployHandler(ctx, v.Helm)
+}
+
+func (r *StackReconciler) upgradeComponent(ctx context.Context, v stack.Component) error {
+	if v.HandlerType == stack.ComponentTypeKubectl {
+		return executor.K8sDeployHandler(ctx, r.RestConfig, v.Kubectl)
+	}
+	return executor.HelmUpgradeHandler(ctx, r.RestConfig, v.Helm, r.readinessTimeout())
+}
+
+func (r *StackReconciler) uninstallComponent(ctx context.Context, v stack.Component) error {
+	if v.HandlerType == stack.ComponentTypeKubectl {
+		return executor.K8sUninstallHandler(ctx, r.RestConfig, v.Kubectl)
+	}
+	return executor.HelmUninstallHandler(ctx, v.Helm)
 }
 
 func (r *StackReconciler) Remove(ctx context.Context, app *appv1.Stack) error {
 	l := log.FromContext(ctx)
 	kl := logger.NewStructuredLogger(-1, os.Stdout)
 
-	if !r.WasStackInstalled(app.Spec.StackName) {
+	key := r.StateKey(app)
+	appState, ok := r.state.get(key)
+	if !ok {
 		l.Info("Already uninstalled", "stack", app.Spec.StackName)
 		return nil
 	}
 
-	manifest, err := GetStackManifest(kl, app)
+	overrides, err := getStackOverrides(app)
+	if err != nil {
+		return err
+	}
+	manifest, err := getStackManifest(kl, app.Spec.StackName,
+		getDesiredOverrides(overrides, appState))
+	if err != nil {
+		return err
+	}
+
+	unlock, err := r.locks.lock(ctx, stackResources(manifest))
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
+	removed := false
 	defer func() {
-		if err := r.Save(ctx); err != nil {
+		if removed {
+			r.state.remove(key)
+		} else {
+			r.state.put(key, appState)
+		}
+		if err := r.Save(ctx, app); err != nil {
 			l.Error(err, "Failed to save state")
 		}
 	}()
 
-	for i := len(manifest.StkDepsIdx) - 1; i >= 0; i-- {
-		componentId := manifest.StkDepsIdx[i]
+	levels, err := stacks.GetInstallLevels(app.Spec.StackName, manifest)
+	if err != nil {
+		return err
+	}
 
-		if !r.WasComponentInstalled(app.Spec.StackName, string(componentId)) {
-			l.Info("Already uninstalled", "component", componentId, "stack", app.Spec.StackName)
-			continue
+	g := &stepGuard{}
+	for _, level := range slices.Backward(levels) {
+		var componentIds []stack.ComponentID
+		for _, componentId := range level {
+			if _, ok := appState.Components[string(componentId)]; !ok {
+				l.Info("Already uninstalled", "component", componentId, "stack", app.Spec.StackName)
+				continue
+			}
+			if _, ok := manifest.Components[componentId]; !ok {
+				return ksctlErrors.WrapError(
+					ksctlErrors.ErrFailedKsctlComponent,
+					kl.NewError(context.Background(), "component not found", "componentId", componentId),
+				)
+			}
+			componentIds = append(componentIds, componentId)
 		}
 
-		if slices.Contains(app.Spec.DisableComponents, string(componentId)) {
-			l.Info("Component disabled", "component", componentId, "stack", app.Spec.StackName)
-			continue
+		steps := make([]appv1.PlanStep, 0, len(componentIds))
+		for _, componentId := range componentIds {
+			steps = append(steps, appv1.PlanStep{ID: string(componentId), Action: appv1.PlanUninstall})
 		}
+		r.reportProgress(ctx, app, steps)
 
-		if v, ok := manifest.Components[componentId]; !ok {
-			return ksctlErrors.WrapError(
-				ksctlErrors.ErrFailedKsctlComponent,
-				kl.NewError(context.Background(), "component not found", "componentId", componentId),
-			)
-		} else {
+		err := r.inParallel(componentIds, func(componentId stack.ComponentID) error {
+			v := manifest.Components[componentId]
 			ver := stacks.GetComponentVersionOverriding(v)
+
+			g.lock()
+			defer g.unlock()
 			l.Info("Component", "Name", componentId, "Version", ver)
-			if v.HandlerType == stack.ComponentTypeKubectl {
-				if k8sErr := executor.K8sUninstallHandler(
-					ctx,
-					r.RestConfig,
-					v.Kubectl,
-				); k8sErr != nil {
-					return k8sErr
-				}
-			} else {
-				if helmErr := executor.HelmUninstallHandler(
-					ctx,
-					v.Helm,
-				); helmErr != nil {
-					return helmErr
-				}
+			setComponentPhase(app, componentId, v, ver, appv1.ComponentUninstalling, nil)
+			r.componentEvent(app, EventUninstalling, componentId, ver, nil)
+			start := time.Now()
+			componentCtx, span := tracing.Start(componentContext(ctx, app, componentId, ver),
+				"Component", tracing.AttrAction.String(string(appv1.PlanUninstall)))
+			err := g.outside(func() error { return r.uninstallComponent(componentCtx, v) })
+			tracing.End(span, err)
+			if err != nil {
+				setComponentPhase(app, componentId, v, ver, appv1.ComponentFailed, err)
+				r.componentEvent(app, EventUninstallFailed, componentId, ver, err)
+				return err
 			}
-			delete(r.state.Stacks[app.Spec.StackName].Components, string(componentId))
+			observeUninstall(app, componentId, start)
+			forgetComponent(app, componentId)
+			delete(appState.Components, string(componentId))
+			setComponentPhase(app, componentId, v, ver, appv1.ComponentUninstalled, nil)
+			r.componentEvent(app, EventUninstalled, componentId, ver, nil)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 	}
 	if wasm.ShouldPerformAdditionalProcessing(stack.ID(app.Spec.StackName)) {
 		if err := wasm.AfterRemoval(ctx, r.Client); err != nil {
 			l.Error(err, "Failed to perform additional processing", "purpose", "wasm/node-annotate")
+			r.event(app, corev1.EventTypeWarning, EventNodeAnnotationFailed, "Failed to remove the wasm node annotations: %v", err)
 			return err
 		}
+		r.event(app, corev1.EventTypeNormal, EventNodesAnnotated, "Removed the wasm node annotations")
 	}
-	delete(r.state.Stacks, app.Spec.StackName)
+	removed = true
 	l.Info("Successfully uninstalled", "stack", app.Spec.StackName)
 	return nil
 }
 
-func (r *StackReconciler) Add(ctx context.Context, app *appv1.Stack) error {
-	l := log.FromContext(ctx)
-	kl := logger.NewStructuredLogger(-1, os.Stdout)
+// Add installs the enabled components of the stack, the ones deployed for the
+// first time by this call are returned in the order they got deployed. When
+// the status holds a plan of the current spec only its steps are carried out,
+// Stacks requiring approval wait for the plan to be approved.
+func (r *StackReconciler) Add(ctx context.Context, app *appv1.Stack) ([]stack.ComponentID, error) {
+	var installed []stack.ComponentID
 
-	manifest, err := GetStackManifest(kl, app)
-	if err != nil {
-		return err
-	}
+	l := log.FromContext(ctx)
 
-	var appState AppState
-	if r.WasStackInstalled(app.Spec.StackName) {
+	key := r.StateKey(app)
+	appState, ok := r.state.get(key)
+	if ok {
 		l.Info("Already installed checking for components", "stack", app.Spec.StackName)
-		appState = r.state.Stacks[app.Spec.StackName]
 	} else {
 		appState = AppState{
 			Components: map[string]ComponentState{},
 		}
 	}
+	appState.StackName = app.Spec.StackName
+	appState.UID = app.UID
+
+	plan, err := appliedPlan(app)
+	if err != nil {
+		return installed, err
+	}
+	manifest, steps, err := planStack(app, appState, plan)
+	if err != nil {
+		return installed, err
+	}
+
+	// Stacks sharing releases or namespaces go one after the other, the later
+	// one then finds the conflict
+	unlock, err := r.locks.lock(ctx, stackResources(manifest))
+	if err != nil {
+		return installed, err
+	}
+	defer unlock()
+
+	if err := findConflict(r.state, key, manifest, app.Spec.DisableComponents); err != nil {
+		return installed, err
+	}
+	if plan != nil {
+		if err := checkPlan(plan.Steps, steps); err != nil {
+			if app.Spec.RequireApproval {
+				// the next attempt records a new plan to approve
+				app.Status.Plan = nil
+			}
+			return installed, err
+		}
+	}
+	if err := checkApproval(app, plan, steps); err != nil {
+		return installed, err
+	}
 
 	defer func() {
-		r.state.Stacks[app.Spec.StackName] = appState
-		if err := r.Save(ctx); err != nil {
+		r.state.put(key, appState)
+		if err := r.Save(ctx, app); err != nil {
 			l.Error(err, "Failed to save state")
 		}
 	}()
 
-	for _, componentId := range manifest.StkDepsIdx {
-		if r.WasComponentInstalled(app.Spec.StackName, string(componentId)) {
-			l.Info("Already installed", "component", componentId, "stack", app.Spec.StackName)
+	installed, err = r.applySteps(ctx, app, manifest, appState, steps)
+	if err != nil {
+		return installed, err
+	}
+	if wasm.ShouldPerformAdditionalProcessing(stack.ID(app.Spec.StackName)) {
+		if err := wasm.AfterInstall(tracing.ContextWithAttributes(ctx, tracing.AttrStackID.String(app.Spec.StackName)), r.Client); err != nil {
+			l.Error(err, "Failed to perform additional processing", "purpose", "wasm/node-annotate")
+			r.event(app, corev1.EventTypeWarning, EventNodeAnnotationFailed, "Failed to annotate the nodes for wasm: %v", err)
+			return installed, err
+		}
+		r.event(app, corev1.EventTypeNormal, EventNodesAnnotated, "Annotated the nodes for wasm")
+	}
+
+	l.Info("Successfully installed", "stack", app.Spec.StackName)
+	return installed, nil
+}
+
+// applySteps carries out the steps of the plan, the ones of a level of the
+// dependency graph side by side.
+func (r *StackReconciler) applySteps(
+	ctx context.Context,
+	app *appv1.Stack,
+	manifest stack.ApplicationStack,
+	appState AppState,
+	steps []appv1.PlanStep,
+) ([]stack.ComponentID, error) {
+	var installed []stack.ComponentID
+
+	levels, err := stacks.GetInstallLevels(app.Spec.StackName, manifest)
+	if err != nil {
+		return installed, err
+	}
+
+	g := &stepGuard{}
+	for _, group := range stepGroups(steps, levels) {
+		r.reportProgress(ctx, app, group)
+
+		byID := make(map[stack.ComponentID]appv1.PlanStep, len(group))
+		componentIds := make([]stack.ComponentID, 0, len(group))
+		for _, step := range group {
+			byID[stack.ComponentID(step.ID)] = step
+			componentIds = append(componentIds, stack.ComponentID(step.ID))
+		}
+
+		err := r.inParallel(componentIds, func(componentId stack.ComponentID) error {
+			step := byID[componentId]
+			stepCtx, span := tracing.Start(componentContext(ctx, app, componentId, step.Version),
+				"Component", tracing.AttrAction.String(string(step.Action)))
+
+			g.lock()
+			defer g.unlock()
+			fresh, err := r.applyStep(stepCtx, g, app, manifest, appState, step)
+			tracing.End(span, err)
+			if fresh {
+				installed = append(installed, componentId)
+			}
+			return err
+		})
+		if err != nil {
+			return installed, err
+		}
+	}
+	return installed, nil
+}
+
+// stepPhase is the phase the component enters with the step, empty when the
+// step leaves it alone.
+func stepPhase(step appv1.PlanStep) appv1.ComponentPhase {
+	switch step.Action {
+	case appv1.PlanInstall:
+		return appv1.ComponentInstalling
+	case appv1.PlanUpgrade:
+		if step.InstalledVersion != step.Version {
+			return appv1.ComponentUpgrading
+		}
+		return appv1.ComponentUpdating
+	case appv1.PlanUninstall:
+		return appv1.ComponentUninstalling
+	}
+	return ""
+}
+
+// reportProgress moves the components of the steps about to be carried out to
+// the phase they enter and persists the status before the long running work
+// starts. Failing to do so doesn't stop the work, the status is written again
+// once it is done.
+func (r *StackReconciler) reportProgress(ctx context.Context, app *appv1.Stack, steps []appv1.PlanStep) {
+	var actions []string
+	uninstallOnly := true
+	for _, step := range steps {
+		phase := stepPhase(step)
+		if len(phase) == 0 {
 			continue
 		}
-		if slices.Contains(app.Spec.DisableComponents, string(componentId)) {
+		updateComponentStatus(app, stack.ComponentID(step.ID), func(c *appv1.ComponentStatus) {
+			c.Phase = phase
+			c.LastError = ""
+		})
+		actions = append(actions, strings.ToLower(string(phase))+" "+step.ID)
+		uninstallOnly = uninstallOnly && step.Action == appv1.PlanUninstall
+	}
+	if len(actions) == 0 {
+		return
+	}
+
+	reason := ReasonReconciling
+	if uninstallOnly {
+		reason = ReasonUninstalling
+	}
+	message := strings.Join(actions, ", ")
+	app.Status.StatusCode = appv1.WorkingOn
+	setStackCondition(app, appv1.ConditionProgressing, metav1.ConditionTrue, reason,
+		strings.ToUpper(message[:1])+message[1:])
+	if err := r.Status().Update(ctx, app); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update status")
+	}
+}
+
+// applyStep carries out a single step of the plan with the guard held, it
+// reports whether the component got deployed for the first time.
+func (r *StackReconciler) applyStep(
+	ctx context.Context,
+	g *stepGuard,
+	app *appv1.Stack,
+	manifest stack.ApplicationStack,
+	appState AppState,
+	step appv1.PlanStep,
+) (bool, error) {
+	l := log.FromContext(ctx)
+
+	componentId := stack.ComponentID(step.ID)
+	v, ok := manifest.Components[componentId]
+	if !ok {
+		l.Info("Already installed", "component", componentId, "stack", app.Spec.StackName)
+		return false, nil
+	}
+	componentState, isInstalled := appState.Components[step.ID]
+	ver := stacks.GetComponentVersionOverriding(v)
+
+	switch step.Action {
+	case appv1.PlanUninstall:
+		l.Info("Uninstalling disabled component", "component", componentId, "stack", app.Spec.StackName, "version", componentState.Ver)
+		setComponentPhase(app, componentId, v, componentState.Ver, appv1.ComponentUninstalling, nil)
+		r.componentEvent(app, EventUninstalling, componentId, componentState.Ver, nil)
+		start := time.Now()
+		if err := g.outside(func() error { return r.uninstallComponent(ctx, v) }); err != nil {
+			setComponentPhase(app, componentId, v, componentState.Ver, appv1.ComponentFailed, err)
+			r.componentEvent(app, EventUninstallFailed, componentId, componentState.Ver, err)
+			return false, err
+		}
+		observeUninstall(app, componentId, start)
+		forgetComponent(app, componentId)
+		r.componentEvent(app, EventUninstalled, componentId, componentState.Ver, nil)
+		delete(appState.Components, step.ID)
+		updateComponentStatus(app, componentId, func(c *appv1.ComponentStatus) {
+			c.PreviousVersion = componentState.Ver
+		})
+		setComponentPhase(app, componentId, v, componentState.Ver, appv1.ComponentDisabled, nil)
+		return false, nil
+
+	case appv1.PlanSkip:
+		if !isInstalled {
 			l.Info("Component disabled", "component", componentId, "stack", app.Spec.StackName)
-			continue
+			setComponentPhase(app, componentId, v, ver, appv1.ComponentDisabled, nil)
+			r.event(app, corev1.EventTypeNormal, EventSkipped, "Component %s version %s is disabled", componentId, ver)
+			return false, nil
 		}
-		if v, ok := manifest.Components[componentId]; !ok {
-			return ksctlErrors.WrapError(
-				ksctlErrors.ErrFailedKsctlComponent,
-				kl.NewError(context.Background(), "component not found", "componentId", componentId),
-			)
-		} else {
-			ver := stacks.GetComponentVersionOverriding(v)
-			l.Info("Component", "Name", componentId, "Version", ver)
-			if v.HandlerType == stack.ComponentTypeKubectl {
-				if k8sErr := executor.K8sDeployHandler(
-					ctx,
-					r.RestConfig,
-					v.Kubectl,
-				); k8sErr != nil {
-					return k8sErr
-				}
-			} else {
-				if helmErr := executor.HelmDeployHandler(
-					ctx,
-					v.Helm,
-				); helmErr != nil {
-					return helmErr
-				}
+
+		l.Info("Already installed", "component", componentId, "stack", app.Spec.StackName)
+		if componentState.Hash == "" {
+			// recorded before hashes were kept, adopt what is deployed
+			hash, err := stacks.GetComponentHash(v)
+			if err != nil {
+				return false, err
 			}
-			appState.Components[string(componentId)] = ComponentState{
-				Ver: ver,
+			componentState = newComponentState(v, ver, hash)
+			appState.Components[step.ID] = componentState
+		}
+		if componentState.WaitingReady {
+			setComponentPhase(app, componentId, v, ver, appv1.ComponentInstalling, nil)
+			if err := r.awaitComponent(ctx, g, app, appState, componentId, v, ver, componentState); err != nil {
+				return false, err
 			}
 		}
-	}
-	if wasm.ShouldPerformAdditionalProcessing(stack.ID(app.Spec.StackName)) {
-		if err := wasm.AfterInstall(ctx, r.Client); err != nil {
-			l.Error(err, "Failed to perform additional processing", "purpose", "wasm/node-annotate")
-			return err
+		updateComponentStatus(app, componentId, func(c *appv1.ComponentStatus) {
+			c.HandlerType = string(v.HandlerType)
+			c.VersionConstraint = componentVersionConstraint(app, componentId)
+			c.DesiredVersion = ver
+			c.InstalledVersion = componentState.Ver
+			c.Phase = appv1.ComponentInstalled
+			c.LastError = ""
+		})
+		recordComponentVersion(app, componentId, componentState.Ver)
+		return false, nil
+
+	case appv1.PlanUpgrade:
+		hash, err := stacks.GetComponentHash(v)
+		if err != nil {
+			return false, err
+		}
+		phase := appv1.ComponentUpdating
+		if componentState.Ver != ver {
+			phase = appv1.ComponentUpgrading
+		}
+		l.Info("Redeploying", "component", componentId, "stack", app.Spec.StackName,
+			"phase", phase, "from", componentState.Ver, "to", ver)
+		setComponentPhase(app, componentId, v, ver, phase, nil)
+		r.componentEvent(app, EventUpgrading, componentId, ver, nil)
+		start := time.Now()
+		if err := g.outside(func() error { return r.upgradeComponent(ctx, v) }); err != nil {
+			setComponentPhase(app, componentId, v, ver, appv1.ComponentFailed, err)
+			r.componentEvent(app, EventUpgradeFailed, componentId, ver, err)
+			return false, err
 		}
+		if err := r.awaitComponent(ctx, g, app, appState, componentId, v, ver, newComponentState(v, ver, hash)); err != nil {
+			r.componentEvent(app, EventUpgradeFailed, componentId, ver, err)
+			return false, err
+		}
+		observeInstall(app, componentId, start)
+		recordComponentVersion(app, componentId, ver)
+		r.componentEvent(app, EventUpgraded, componentId, ver, nil)
+		if phase == appv1.ComponentUpgrading {
+			updateComponentStatus(app, componentId, func(c *appv1.ComponentStatus) {
+				c.PreviousVersion = componentState.Ver
+			})
+		}
+		setComponentPhase(app, componentId, v, ver, appv1.ComponentInstalled, nil)
+		return false, nil
 	}
 
-	l.Info("Successfully installed", "stack", app.Spec.StackName)
-	return nil
+	hash, err := stacks.GetComponentHash(v)
+	if err != nil {
+		return false, err
+	}
+	l.Info("Component", "Name", componentId, "Version", ver)
+	setComponentPhase(app, componentId, v, ver, appv1.ComponentInstalling, nil)
+	r.componentEvent(app, EventInstalling, componentId, ver, nil)
+	start := time.Now()
+	if err := g.outside(func() error { return r.installComponent(ctx, v) }); err != nil {
+		setComponentPhase(app, componentId, v, ver, appv1.ComponentFailed, err)
+		r.componentEvent(app, EventInstallFailed, componentId, ver, err)
+		return false, err
+	}
+	if err := r.awaitComponent(ctx, g, app, appState, componentId, v, ver, newComponentState(v, ver, hash)); err != nil {
+		r.componentEvent(app, EventInstallFailed, componentId, ver, err)
+		return true, err
+	}
+	setComponentPhase(app, componentId, v, ver, appv1.ComponentInstalled, nil)
+	observeInstall(app, componentId, start)
+	recordComponentVersion(app, componentId, ver)
+	r.componentEvent(app, EventInstalled, componentId, ver, nil)
+	return true, nil
 }
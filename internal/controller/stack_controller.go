@@ -18,16 +18,25 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/tracing"
 )
 
 // StackReconciler reconciles a Stack object
@@ -35,10 +44,49 @@ type StackReconciler struct {
 	client.Client
 	RestConfig *rest.Config
 	Scheme     *runtime.Scheme
-	state      *StackState
+	// Storage defaults to ConfigMaps in StateNamespace
+	Storage Storage
+	// StateNamespace defaults to DefaultStateNamespace
+	StateNamespace string
+	// DriftCheckInterval is how often installed stacks are checked for drift,
+	// zero disables the periodic checks
+	DriftCheckInterval time.Duration
+	// ReadinessTimeout bounds the wait for a component to become ready before
+	// the next one gets deployed, helm upgrades wait as long for their
+	// releases. Defaults to DefaultReadinessTimeout
+	ReadinessTimeout time.Duration
+	// MaxRetries is how many failed attempts in a row a stack gets before it
+	// stalls, zero retries forever
+	MaxRetries int32
+	// Recorder publishes the lifecycle of the components as events on the Stack
+	Recorder record.EventRecorder
+	// MaxConcurrentReconciles is how many Stacks get reconciled at once, Stacks
+	// sharing helm releases or namespaces still go one after the other
+	MaxConcurrentReconciles int
+	// MaxParallelComponents bounds how many components of a stack are installed
+	// at once, defaults to DefaultMaxParallelComponents
+	MaxParallelComponents int
+
+	initMu sync.Mutex
+	state  *StackState
+	locks  resourceLocks
 }
 
+func (r *StackReconciler) stateNamespace() string {
+	if len(r.StateNamespace) == 0 {
+		return DefaultStateNamespace
+	}
+	return r.StateNamespace
+}
+
+// InitializeStorage loads the state once, however many workers ask for it.
 func (r *StackReconciler) InitializeStorage(ctx context.Context) error {
+	r.initMu.Lock()
+	defer r.initMu.Unlock()
+
+	if r.Storage == nil {
+		r.Storage = NewConfigMapStorage(r.Client, r.stateNamespace())
+	}
 	if r.state == nil {
 		return r.Load(ctx)
 	}
@@ -50,9 +98,18 @@ const managerFinalizer string = "finalizer.stack.app.ksctl.com"
 // +kubebuilder:rbac:groups=app.ksctl.com,resources=stacks,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=app.ksctl.com,resources=stacks/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=app.ksctl.com,resources=stacks/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=*,resources=*,verbs=*
 
 func (r *StackReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = tracing.ContextWithAttributes(ctx, tracing.AttrStack.String(req.Name))
+	ctx, span := tracing.Start(ctx, "Reconcile")
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *StackReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	l := log.FromContext(ctx)
 	l.Info("Reconciling Stack", "stack", req.NamespacedName)
 
@@ -73,12 +130,39 @@ func (r *StackReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 	if instance.Status.StatusCode == "" {
 		instance.Status.StatusCode = appv1.WorkingOn
+		setStackCondition(instance, appv1.ConditionReady, metav1.ConditionUnknown, ReasonReconciling, "Stack is being reconciled")
+		setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionTrue, ReasonReconciling, "Stack is being reconciled")
 		if err := r.Status().Update(ctx, instance); err != nil {
 			l.Error(err, "Failed to update initial status")
 			return ctrl.Result{RequeueAfter: time.Second * 5}, err
 		}
 	}
 
+	forced, err := r.consumeRetryAnnotation(ctx, instance)
+	if err != nil {
+		l.Error(err, "Failed to remove the retry annotation")
+		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	}
+	if !forced && retriesOutdated(instance) {
+		l.Info("Stack changed since it failed, starting over")
+		instance.Status.LastFailure = nil
+		resetRetries(instance)
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "Failed to update status")
+			return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		}
+	}
+	if !forced {
+		delay, stalled := retryDelay(instance)
+		if stalled {
+			l.Info("Stack stalled, waiting for it to change or a forced retry")
+			return ctrl.Result{}, nil
+		}
+		if delay > 0 {
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+	}
+
 	if !instance.DeletionTimestamp.IsZero() {
 		return r.processDeletion(ctx, instance)
 	}
@@ -92,23 +176,146 @@ func (r *StackReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 func (r *StackReconciler) processInstall(ctx context.Context, instance *appv1.Stack) (ctrl.Result, error) {
 	l := log.FromContext(ctx)
+	defer recordStackStatus(instance)
 
-	if err := r.Add(ctx, instance); err != nil {
+	if isInstallHalted(instance) {
+		l.Info("Install halted by the failure policy until the Stack changes",
+			"action", instance.Status.LastFailure.Action)
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	r.checkUpdates(ctx, instance, now)
+
+	if getStackMode(instance) == appv1.ModePlan {
+		return r.processPlan(ctx, instance)
+	}
+
+	installed, err := r.Add(ctx, instance)
+	var drifted []string
+	if err == nil {
+		drifted, err = r.processDrift(ctx, instance, now)
+	}
+
+	if errors.Is(err, ErrApprovalPending) {
+		l.Info("Plan waits for approval", "plan", instance.Status.Plan.Hash)
+
+		instance.Status.StatusCode = appv1.WorkingOn
+		instance.Status.ReasonOfFailure = ""
+		setStackCondition(instance, appv1.ConditionPendingApproval, metav1.ConditionTrue, ReasonAwaitingApproval, err.Error())
+		setStackCondition(instance, appv1.ConditionPlanned, metav1.ConditionTrue, ReasonPlanReady, planSummary(instance.Status.Plan))
+		setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionFalse, ReasonAwaitingApproval, "Waiting for the plan to be approved")
+
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "Failed to update status")
+			return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		}
+
+		// setting the annotation triggers the next reconcile
+		return ctrl.Result{}, nil
+	} else if errors.Is(err, ErrStackConflict) {
+		l.Info("Stack conflicts with another Stack", "reason", err.Error())
+		recordStackFailure(instance, OperationInstall, err)
+
+		instance.Status.StatusCode = appv1.Failure
+		instance.Status.ReasonOfFailure = err.Error()
+		setStackCondition(instance, appv1.ConditionConflict, metav1.ConditionTrue, ReasonConflict, err.Error())
+		setStackCondition(instance, appv1.ConditionReady, metav1.ConditionFalse, ReasonConflict, err.Error())
+		setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionFalse, ReasonConflict, "Waiting for the conflict to be resolved")
+
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "Failed to update status")
+			return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		}
+
+		// the other Stack going away is not something we get notified about
+		return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+	} else if err != nil {
 		l.Error(err, "Failed to install app")
+		recordStackFailure(instance, OperationInstall, err)
 
 		instance.Status.StatusCode = appv1.Failure
 		instance.Status.ReasonOfFailure = err.Error() + "\nFailed to install app"
+		setStackCondition(instance, appv1.ConditionReady, metav1.ConditionFalse, ReasonInstallFailed, err.Error())
+		setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionFalse, ReasonInstallFailed, "Failed to install app")
+		r.handleInstallFailure(ctx, instance, installed, err)
+		var requeueAfter time.Duration
+		if !isInstallHalted(instance) {
+			requeueAfter = r.recordFailure(instance, err)
+		}
 
 		if err := r.Status().Update(ctx, instance); err != nil {
 			l.Error(err, "Failed to update status")
 			return ctrl.Result{RequeueAfter: time.Second * 5}, err
 		}
 
+		// the error is not handed back, the requeue would skip the backoff
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	instance.Status.LastFailure = nil
+	if instance.Spec.RequireApproval && isPlanApproved(instance, instance.Status.Plan) {
+		setStackCondition(instance, appv1.ConditionPendingApproval, metav1.ConditionFalse, ReasonPlanApproved,
+			"Applied plan "+instance.Status.Plan.Hash)
+	} else {
+		meta.RemoveStatusCondition(&instance.Status.Conditions, appv1.ConditionPendingApproval)
+	}
+	instance.Status.Plan = nil
+	meta.RemoveStatusCondition(&instance.Status.Conditions, appv1.ConditionPlanned)
+	resetRetries(instance)
+	setStackCondition(instance, appv1.ConditionConflict, metav1.ConditionFalse, ReasonNoConflict, "No other Stack owns the resources of this stack")
+	if len(drifted) != 0 {
+		instance.Status.StatusCode = appv1.Failure
+		instance.Status.ReasonOfFailure = strings.Join(drifted, "\n")
+		setStackCondition(instance, appv1.ConditionReady, metav1.ConditionFalse, ReasonDriftDetected, "Installed components drifted")
+		setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionFalse, ReasonDriftDetected, "Installed components drifted")
+	} else {
+		instance.Status.StatusCode = appv1.Success
+		instance.Status.ReasonOfFailure = ""
+		setStackCondition(instance, appv1.ConditionReady, metav1.ConditionTrue, ReasonInstallSucceeded, "All enabled components are installed")
+		setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionFalse, ReasonInstallSucceeded, "All enabled components are installed")
+	}
+	nextWindow := recordUpdates(instance, now)
+	if err := r.Status().Update(ctx, instance); err != nil {
+		l.Error(err, "Failed to update status")
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
 	}
 
+	requeueAfter := r.nextDriftCheckIn(instance, now)
+	if d := nextUpdateIn(instance, nextWindow, now); d > 0 && (requeueAfter == 0 || d < requeueAfter) {
+		requeueAfter = d
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// processPlan computes the plan of the Stack, nothing gets applied until the
+// mode is switched to apply.
+func (r *StackReconciler) processPlan(ctx context.Context, instance *appv1.Stack) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+
+	if err := r.Plan(ctx, instance); err != nil {
+		l.Error(err, "Failed to plan app")
+		recordStackFailure(instance, OperationPlan, err)
+
+		instance.Status.StatusCode = appv1.Failure
+		instance.Status.ReasonOfFailure = err.Error() + "\nFailed to plan app"
+		setStackCondition(instance, appv1.ConditionPlanned, metav1.ConditionFalse, ReasonPlanFailed, err.Error())
+		setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionFalse, ReasonPlanFailed, "Failed to plan app")
+		requeueAfter := r.recordFailure(instance, err)
+
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "Failed to update status")
+			return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	instance.Status.LastFailure = nil
+	resetRetries(instance)
 	instance.Status.StatusCode = appv1.Success
 	instance.Status.ReasonOfFailure = ""
+	setStackCondition(instance, appv1.ConditionPlanned, metav1.ConditionTrue, ReasonPlanReady, planSummary(instance.Status.Plan))
+	setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionFalse, ReasonPlanReady, "Waiting for the plan to be applied")
 	if err := r.Status().Update(ctx, instance); err != nil {
 		l.Error(err, "Failed to update status")
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
@@ -126,13 +333,26 @@ func (r *StackReconciler) processDeletion(ctx context.Context, instance *appv1.S
 
 	if err := r.Remove(ctx, instance); err != nil {
 		l.Error(err, "Failed to uninstall app")
-		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		recordStackFailure(instance, OperationUninstall, err)
+
+		instance.Status.StatusCode = appv1.Failure
+		instance.Status.ReasonOfFailure = err.Error() + "\nFailed to uninstall app"
+		setStackCondition(instance, appv1.ConditionReady, metav1.ConditionFalse, ReasonUninstallFailed, err.Error())
+		setStackCondition(instance, appv1.ConditionProgressing, metav1.ConditionFalse, ReasonUninstallFailed, "Failed to uninstall app")
+		requeueAfter := r.recordFailure(instance, err)
+
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "Failed to update status")
+			return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
 	if _, err := r.removeFinalizer(ctx, instance); err != nil {
 		l.Error(err, "Failed to remove finalizer")
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
 	}
+	forgetStack(instance)
 
 	return ctrl.Result{}, nil
 }
@@ -142,6 +362,7 @@ func (r *StackReconciler) addFinalizer(ctx context.Context, instance *appv1.Stac
 	if err := r.Update(ctx, instance); err != nil {
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
 	}
+	r.event(instance, corev1.EventTypeNormal, EventFinalizerAdded, "Added finalizer %s", managerFinalizer)
 	return ctrl.Result{Requeue: true}, nil
 }
 
@@ -162,6 +383,7 @@ func (r *StackReconciler) removeFinalizer(ctx context.Context, instance *appv1.S
 	if err := r.Update(ctx, instance); err != nil {
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
 	}
+	r.event(instance, corev1.EventTypeNormal, EventFinalizerRemoved, "Removed finalizer %s, all components are uninstalled", managerFinalizer)
 	return ctrl.Result{}, nil
 }
 
@@ -170,5 +392,6 @@ func (r *StackReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&appv1.Stack{}).
 		Named("stack").
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+	"github.com/ksctl/ksctl/v2/pkg/poller"
+)
+
+var _ = Describe("Stack helpers", func() {
+	It("should only treat explicit versions as pinned", func() {
+		app := &appv1.Stack{
+			Spec: appv1.StackSpec{
+				StackName: "gitops-standard",
+				Overrides: &apiextensionsv1.JSON{
+					Raw: []byte(`{"argocd":{"version":"v2.13.0"},"argorollouts":{"version":"latest","namespace":"ro"}}`),
+				},
+			},
+		}
+
+		overrides, err := getStackOverrides(app)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isVersionPinned(overrides, "argocd")).To(BeTrue())
+		Expect(isVersionPinned(overrides, "argorollouts")).To(BeFalse())
+		Expect(isVersionPinned(overrides, "istio")).To(BeFalse())
+		Expect(isVersionPinned(nil, "argocd")).To(BeFalse())
+	})
+	It("should keep unpinned installed components on their installed version", func() {
+		overrides := map[string]map[string]any{
+			"argocd":       {"version": "v2.13.0"},
+			"argorollouts": {"namespace": "ro"},
+		}
+		appState := AppState{Components: map[string]ComponentState{
+			"argocd":       {Ver: "v2.12.0"},
+			"argorollouts": {Ver: "v1.7.2"},
+			"istio":        {Ver: "1.22.4"},
+		}}
+
+		desired := getDesiredOverrides(overrides, appState)
+		Expect(desired["argocd"]["version"]).To(Equal("v2.13.0"))
+		Expect(desired["argorollouts"]).To(Equal(map[string]any{"namespace": "ro", "version": "v1.7.2"}))
+		Expect(desired["istio"]["version"]).To(Equal("1.22.4"))
+		Expect(overrides["argorollouts"]).NotTo(HaveKey("version"))
+	})
+	It("should keep installed components on their version while it satisfies the constraint", func() {
+		overrides := map[string]map[string]any{
+			"istio":        {"version": "~1.22"},
+			"argorollouts": {"version": ">=1.8 <1.9"},
+		}
+		appState := AppState{Components: map[string]ComponentState{
+			"istio":        {Ver: "1.22.4"},
+			"argorollouts": {Ver: "v1.7.2"},
+		}}
+
+		desired := getDesiredOverrides(overrides, appState)
+		Expect(desired["istio"]["version"]).To(Equal("1.22.4"))
+		Expect(desired["argorollouts"]["version"]).To(Equal(">=1.8 <1.9"))
+		Expect(versionConstraint(overrides, "istio")).To(Equal("~1.22"))
+		Expect(versionConstraint(map[string]map[string]any{"istio": {"version": "1.22.4"}}, "istio")).To(BeEmpty())
+	})
+	It("should render the kwasm operator of spinkube the same once installed", func() {
+		shims := []string{"v0.15.1"}
+		poller.InitSharedGithubReleaseFakePoller(func(org, repo string) ([]string, error) {
+			if repo == "containerd-shim-spin" {
+				return shims, nil
+			}
+			return []string{"v0.4.0"}, nil
+		})
+		kl := logger.NewStructuredLogger(-1, os.Stdout)
+		kwasmHash := func(overrides map[string]map[string]any, appState AppState) (string, AppState) {
+			manifest, err := getStackManifest(kl, "wasm/spinkube-standard", getDesiredOverrides(overrides, appState))
+			Expect(err).NotTo(HaveOccurred())
+			installed := AppState{Components: map[string]ComponentState{}}
+			for id, component := range manifest.Components {
+				installed.Components[string(id)] = ComponentState{Ver: stacks.GetComponentVersionOverriding(component)}
+			}
+			hash, err := stacks.GetComponentHash(manifest.Components["kwasm-operator"])
+			Expect(err).NotTo(HaveOccurred())
+			return hash, installed
+		}
+
+		first, installed := kwasmHash(nil, AppState{})
+		second, _ := kwasmHash(nil, installed)
+		Expect(second).To(Equal(first))
+
+		pinned := map[string]map[string]any{"kwasm-operator": {"shimVersion": "v0.15.1"}}
+		first, installed = kwasmHash(pinned, AppState{})
+		shims = []string{"v0.16.0", "v0.15.1"}
+		second, _ = kwasmHash(pinned, installed)
+		Expect(second).To(Equal(first), "the shim is pinned")
+	})
+
+	It("should persist the progress before acting on the components", func() {
+		ctx := context.Background()
+		app := newStack("gitops", "gitops-standard")
+		c := newFakeClient(app)
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(app), app)).To(Succeed())
+		app.Status.StatusCode = appv1.Success
+		app.Status.Components = []appv1.ComponentStatus{
+			{ID: "argocd", Phase: appv1.ComponentInstalled, InstalledVersion: "v2.12.0"},
+			{ID: "argorollouts", Phase: appv1.ComponentInstalled, InstalledVersion: "v1.7.2"},
+		}
+		r := &StackReconciler{Client: c}
+
+		r.reportProgress(ctx, app, []appv1.PlanStep{
+			{ID: "argocd", Action: appv1.PlanUpgrade, InstalledVersion: "v2.12.0", Version: "v2.13.0"},
+			{ID: "argorollouts", Action: appv1.PlanSkip},
+		})
+		stored := &appv1.Stack{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(app), stored)).To(Succeed())
+		Expect(stored.Status.StatusCode).To(Equal(appv1.WorkingOn))
+		Expect(stored.Status.Components[0].Phase).To(Equal(appv1.ComponentUpgrading))
+		Expect(stored.Status.Components[1].Phase).To(Equal(appv1.ComponentInstalled))
+		cond := meta.FindStatusCondition(stored.Status.Conditions, appv1.ConditionProgressing)
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(ReasonReconciling))
+		Expect(cond.Message).To(Equal("Upgrading argocd"))
+
+		r.reportProgress(ctx, app, []appv1.PlanStep{{ID: "argorollouts", Action: appv1.PlanUninstall}})
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(app), stored)).To(Succeed())
+		Expect(stored.Status.Components[1].Phase).To(Equal(appv1.ComponentUninstalling))
+		Expect(meta.FindStatusCondition(stored.Status.Conditions, appv1.ConditionProgressing).Reason).To(Equal(ReasonUninstalling))
+	})
+})
@@ -0,0 +1,396 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/apps"
+	"github.com/ksctl/ka/internal/policy"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ka/internal/tracing"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+const (
+	ReasonAdmitted        string = "Admitted"
+	ReasonPolicyViolation string = "PolicyViolation"
+)
+
+const (
+	EventPolicyViolation string = "PolicyViolation"
+	EventStackApplied    string = "StackApplied"
+)
+
+const instanceFinalizer string = "finalizer.stackinstance.app.ksctl.com"
+
+// StackInstanceReconciler reconciles a StackInstance object, the admitted
+// instances get installed as cluster-scoped Stacks.
+type StackInstanceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Recorder publishes the admission of the StackInstance as events on it
+	Recorder record.EventRecorder
+}
+
+// instanceStackName is the name of the Stack installing the StackInstance,
+// namespaces can't hold a dot so the name is unique.
+func instanceStackName(instance *appv1.StackInstance) string {
+	return instance.Namespace + "." + instance.Name
+}
+
+// +kubebuilder:rbac:groups=app.ksctl.com,resources=stackinstances,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=app.ksctl.com,resources=stackinstances/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=app.ksctl.com,resources=stackinstances/finalizers,verbs=update
+// +kubebuilder:rbac:groups=app.ksctl.com,resources=stackpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+func (r *StackInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = tracing.ContextWithAttributes(ctx, tracing.AttrStack.String(req.String()))
+	ctx, span := tracing.Start(ctx, "Reconcile")
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *StackInstanceReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+	l.Info("Reconciling StackInstance", "instance", req.NamespacedName)
+
+	instance := &appv1.StackInstance{}
+	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return ctrl.Result{}, nil
+		}
+		l.Error(err, "Failed to get StackInstance")
+		return ctrl.Result{RequeueAfter: time.Second * 10}, err
+	}
+
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.processDeletion(ctx, instance)
+	}
+
+	if !slices.Contains(instance.Finalizers, instanceFinalizer) {
+		instance.Finalizers = append(instance.Finalizers, instanceFinalizer)
+		if err := r.Update(ctx, instance); err != nil {
+			return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	return r.processInstance(ctx, instance)
+}
+
+func (r *StackInstanceReconciler) processInstance(ctx context.Context, instance *appv1.StackInstance) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: instance.Namespace}, ns); err != nil {
+		l.Error(err, "Failed to get the namespace of the StackInstance")
+		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	}
+	policies := &appv1.StackPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		l.Error(err, "Failed to list StackPolicies")
+		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	}
+
+	admission, err := policy.Admit(policies.Items, ns, instance.Spec.StackSpec)
+	if err != nil {
+		// whatever got installed before is left in place, it is neither
+		// changed nor removed until the instance is admitted or deleted
+		l.Info("StackInstance not admitted", "reason", err.Error())
+		r.event(instance, corev1.EventTypeWarning, EventPolicyViolation, "%v", err)
+
+		instance.Status.StatusCode = appv1.Failure
+		instance.Status.ReasonOfFailure = err.Error()
+		setInstanceCondition(instance, appv1.ConditionAdmitted, metav1.ConditionFalse, ReasonPolicyViolation, err.Error())
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "Failed to update status")
+			return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		}
+		// a change to the StackPolicies triggers the next reconcile
+		return ctrl.Result{}, nil
+	}
+
+	stk, err := r.applyStack(ctx, instance, admission)
+	if err != nil {
+		l.Error(err, "Failed to apply the Stack of the StackInstance")
+		instance.Status.StatusCode = appv1.Failure
+		instance.Status.ReasonOfFailure = err.Error()
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "Failed to update status")
+		}
+		return ctrl.Result{RequeueAfter: time.Second * 10}, err
+	}
+
+	mirrorStackStatus(instance, stk)
+	setInstanceCondition(instance, appv1.ConditionAdmitted, metav1.ConditionTrue, ReasonAdmitted,
+		"Installed as Stack "+stk.Name)
+	if err := r.Status().Update(ctx, instance); err != nil {
+		l.Error(err, "Failed to update status")
+		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	}
+	// the Stack changing triggers the next reconcile
+	return ctrl.Result{}, nil
+}
+
+// applyStack creates or updates the Stack installing the instance. The retry
+// annotation is handed over to the Stack and the approved plan follows the one
+// of the instance, so the team can act on its Stack without access to it.
+// Unless the policy requires approval, then it's up to whoever may annotate
+// the Stack.
+func (r *StackInstanceReconciler) applyStack(
+	ctx context.Context,
+	instance *appv1.StackInstance,
+	admission policy.Admission,
+) (*appv1.Stack, error) {
+	_, retry := instance.Annotations[appv1.RetryAnnotation]
+
+	stk := &appv1.Stack{ObjectMeta: metav1.ObjectMeta{Name: instanceStackName(instance)}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, stk, func() error {
+		if !stk.CreationTimestamp.IsZero() &&
+			(stk.Labels[appv1.InstanceNamespaceLabel] != instance.Namespace ||
+				stk.Labels[appv1.InstanceNameLabel] != instance.Name) {
+			return fmt.Errorf("the Stack %s already exists and doesn't belong to the StackInstance", stk.Name)
+		}
+		if stk.Labels == nil {
+			stk.Labels = map[string]string{}
+		}
+		stk.Labels[appv1.InstanceNamespaceLabel] = instance.Namespace
+		stk.Labels[appv1.InstanceNameLabel] = instance.Name
+
+		if stk.Annotations == nil {
+			stk.Annotations = map[string]string{}
+		}
+		if !admission.RequireApproval {
+			if hash, ok := instance.Annotations[appv1.ApprovedPlanAnnotation]; ok {
+				stk.Annotations[appv1.ApprovedPlanAnnotation] = hash
+			} else {
+				delete(stk.Annotations, appv1.ApprovedPlanAnnotation)
+			}
+		}
+		if retry {
+			stk.Annotations[appv1.RetryAnnotation] = "true"
+		}
+
+		current := stk.Spec
+		stk.Spec = admission.Spec
+		return keepPinnedVersions(&stk.Spec, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if op != controllerutil.OperationResultNone {
+		r.event(instance, corev1.EventTypeNormal, EventStackApplied, "Stack %s %s", stk.Name, op)
+	}
+
+	if retry {
+		delete(instance.Annotations, appv1.RetryAnnotation)
+		if err := r.Update(ctx, instance); err != nil {
+			return nil, err
+		}
+	}
+	return stk, nil
+}
+
+// keepPinnedVersions carries the versions pinned on the Stack, including the
+// ones of stacks.GetVersionPins, over to the spec rendered from the instance,
+// where they are left floating. The defaulting
+// webhook only pins them when the Stack gets created, without this the Stack
+// would follow the latest releases from the next update on.
+func keepPinnedVersions(spec *appv1.StackSpec, current appv1.StackSpec) error {
+	if current.Overrides == nil || current.StackName != spec.StackName {
+		return nil
+	}
+	pinned, err := getStackOverrides(&appv1.Stack{Spec: current})
+	if err != nil {
+		return err
+	}
+	overrides, err := getStackOverrides(&appv1.Stack{Spec: *spec})
+	if err != nil {
+		return err
+	}
+
+	kept := false
+	for id, o := range pinned {
+		ver, ok := o["version"].(string)
+		// constraints only come from the instance, which dropped them
+		if !ok || ver == "latest" || apps.IsVersionConstraint(ver) {
+			continue
+		}
+		if isVersionPinned(overrides, stack.ComponentID(id)) || slices.Contains(spec.DisableComponents, id) ||
+			spec.Updates.ChannelOf(id) != appv1.ChannelPinned {
+			continue
+		}
+		if overrides == nil {
+			overrides = map[string]map[string]any{}
+		}
+		if overrides[id] == nil {
+			overrides[id] = map[string]any{}
+		}
+		overrides[id]["version"] = ver
+		kept = true
+	}
+	for _, pin := range stacks.GetVersionPins(spec.StackName) {
+		id := string(pin.Component)
+		ver, ok := pinned[id][pin.Key].(string)
+		if !ok || ver == "latest" || slices.Contains(spec.DisableComponents, id) {
+			continue
+		}
+		if _, ok := overrides[id][pin.Key]; ok {
+			continue
+		}
+		if overrides == nil {
+			overrides = map[string]map[string]any{}
+		}
+		if overrides[id] == nil {
+			overrides[id] = map[string]any{}
+		}
+		overrides[id][pin.Key] = ver
+		kept = true
+	}
+	if !kept {
+		return nil
+	}
+
+	raw, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	spec.Overrides = &apiextensionsv1.JSON{Raw: raw}
+	return nil
+}
+
+// mirrorStackStatus shows the status of the Stack on the instance, the state
+// of the manager is kept to the Stack.
+func mirrorStackStatus(instance *appv1.StackInstance, stk *appv1.Stack) {
+	admitted := meta.FindStatusCondition(instance.Status.Conditions, appv1.ConditionAdmitted)
+
+	instance.Status.StackStatus = *stk.Status.DeepCopy()
+	instance.Status.State = nil
+	instance.Status.Stack = stk.Name
+	if admitted != nil {
+		meta.SetStatusCondition(&instance.Status.Conditions, *admitted)
+	}
+}
+
+func (r *StackInstanceReconciler) processDeletion(ctx context.Context, instance *appv1.StackInstance) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+
+	if !slices.Contains(instance.Finalizers, instanceFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	stk := &appv1.Stack{}
+	err := r.Get(ctx, client.ObjectKey{Name: instanceStackName(instance)}, stk)
+	if client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	}
+	if err == nil && stk.Labels[appv1.InstanceNamespaceLabel] == instance.Namespace &&
+		stk.Labels[appv1.InstanceNameLabel] == instance.Name {
+		if stk.DeletionTimestamp.IsZero() {
+			l.Info("Deleting the Stack of the StackInstance", "stack", stk.Name)
+			if err := r.Delete(ctx, stk); client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{RequeueAfter: time.Second * 5}, err
+			}
+		}
+
+		mirrorStackStatus(instance, stk)
+		if err := r.Status().Update(ctx, instance); err != nil {
+			l.Error(err, "Failed to update status")
+		}
+		// the Stack going away triggers the next reconcile
+		return ctrl.Result{}, nil
+	}
+
+	instance.Finalizers = slices.DeleteFunc(instance.Finalizers, func(f string) bool { return f == instanceFinalizer })
+	if err := r.Update(ctx, instance); err != nil {
+		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func setInstanceCondition(instance *appv1.StackInstance, condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: instance.Generation,
+	})
+}
+
+// event records an event on the StackInstance, nothing happens without a recorder.
+func (r *StackInstanceReconciler) event(instance *appv1.StackInstance, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(instance, eventType, reason, messageFmt, args...)
+}
+
+// stackToInstance maps a Stack installing a StackInstance back to it.
+func stackToInstance(_ context.Context, obj client.Object) []reconcile.Request {
+	ns, name := obj.GetLabels()[appv1.InstanceNamespaceLabel], obj.GetLabels()[appv1.InstanceNameLabel]
+	if len(ns) == 0 || len(name) == 0 {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: ns, Name: name}}}
+}
+
+// policyToInstances re-evaluates every StackInstance once a StackPolicy changes.
+func (r *StackInstanceReconciler) policyToInstances(ctx context.Context, _ client.Object) []reconcile.Request {
+	instances := &appv1.StackInstanceList{}
+	if err := r.List(ctx, instances); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list StackInstances")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(instances.Items))
+	for _, instance := range instances.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&instance)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *StackInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&appv1.StackInstance{}).
+		Watches(&appv1.Stack{}, handler.EnqueueRequestsFromMapFunc(stackToInstance)).
+		Watches(&appv1.StackPolicy{}, handler.EnqueueRequestsFromMapFunc(r.policyToInstances)).
+		Named("stackinstance").
+		Complete(r)
+}
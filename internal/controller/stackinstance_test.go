@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+var _ = Describe("StackInstance", func() {
+	ctx := context.Background()
+
+	newInstance := func() *appv1.StackInstance {
+		return &appv1.StackInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "gitops", Namespace: "team-a"},
+			Spec: appv1.StackInstanceSpec{StackSpec: appv1.StackSpec{
+				StackName:         "gitops-standard",
+				DisableComponents: []string{"argorollouts"},
+			}},
+		}
+	}
+	teamNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+	allowGitops := &appv1.StackPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+		Spec: appv1.StackPolicySpec{
+			Namespaces: []string{"team-a"},
+			Stacks:     []appv1.AllowedStack{{StackName: "gitops-standard", Components: []string{"argocd"}}},
+		},
+	}
+
+	reconcileInstance := func(r *StackInstanceReconciler, instance *appv1.StackInstance) {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(instance)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(instance), instance)).To(Succeed())
+	}
+
+	It("should install an admitted instance as a Stack and mirror its status", func() {
+		instance := newInstance()
+		c := newFakeClient(teamNamespace.DeepCopy(), allowGitops.DeepCopy(), instance)
+		r := &StackInstanceReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+		reconcileInstance(r, instance)
+		Expect(instance.Finalizers).To(ContainElement(instanceFinalizer))
+		reconcileInstance(r, instance)
+
+		stk := &appv1.Stack{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, stk)).To(Succeed())
+		Expect(stk.Labels).To(HaveKeyWithValue(appv1.InstanceNamespaceLabel, "team-a"))
+		Expect(stk.Labels).To(HaveKeyWithValue(appv1.InstanceNameLabel, "gitops"))
+		Expect(stk.Spec.DisableComponents).To(Equal([]string{"argorollouts"}))
+		overrides := map[string]map[string]any{}
+		Expect(json.Unmarshal(stk.Spec.Overrides.Raw, &overrides)).To(Succeed())
+		Expect(overrides["argocd"]).To(HaveKeyWithValue("namespace", "team-a"))
+
+		Expect(instance.Status.Stack).To(Equal("team-a.gitops"))
+		Expect(meta.IsStatusConditionTrue(instance.Status.Conditions, appv1.ConditionAdmitted)).To(BeTrue())
+
+		stk.Status.StatusCode = appv1.Success
+		setStackCondition(stk, appv1.ConditionReady, metav1.ConditionTrue, ReasonInstallSucceeded, "All enabled components are installed")
+		Expect(c.Status().Update(ctx, stk)).To(Succeed())
+		instance.Annotations = map[string]string{appv1.RetryAnnotation: "true", appv1.ApprovedPlanAnnotation: "abc"}
+		Expect(c.Update(ctx, instance)).To(Succeed())
+
+		reconcileInstance(r, instance)
+		Expect(instance.Status.StatusCode).To(Equal(appv1.Success))
+		Expect(meta.IsStatusConditionTrue(instance.Status.Conditions, appv1.ConditionReady)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(instance.Status.Conditions, appv1.ConditionAdmitted)).To(BeTrue())
+		Expect(instance.Annotations).NotTo(HaveKey(appv1.RetryAnnotation))
+
+		Expect(c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, stk)).To(Succeed())
+		Expect(stk.Annotations).To(HaveKeyWithValue(appv1.RetryAnnotation, "true"))
+		Expect(stk.Annotations).To(HaveKeyWithValue(appv1.ApprovedPlanAnnotation, "abc"))
+	})
+
+	It("should leave the approval to the Stack when the policy requires it", func() {
+		instance := newInstance()
+		instance.Annotations = map[string]string{appv1.ApprovedPlanAnnotation: "abc"}
+		requireApproval := allowGitops.DeepCopy()
+		requireApproval.Spec.Stacks[0].RequireApproval = true
+		c := newFakeClient(teamNamespace.DeepCopy(), requireApproval, instance)
+		r := &StackInstanceReconciler{Client: c}
+
+		reconcileInstance(r, instance)
+		reconcileInstance(r, instance)
+
+		stk := &appv1.Stack{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, stk)).To(Succeed())
+		Expect(stk.Spec.RequireApproval).To(BeTrue())
+		Expect(stk.Annotations).NotTo(HaveKey(appv1.ApprovedPlanAnnotation))
+
+		// approved by an admin on the Stack
+		stk.Annotations = map[string]string{appv1.ApprovedPlanAnnotation: "def"}
+		Expect(c.Update(ctx, stk)).To(Succeed())
+		delete(instance.Annotations, appv1.ApprovedPlanAnnotation)
+		Expect(c.Update(ctx, instance)).To(Succeed())
+
+		reconcileInstance(r, instance)
+		Expect(c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, stk)).To(Succeed())
+		Expect(stk.Annotations).To(HaveKeyWithValue(appv1.ApprovedPlanAnnotation, "def"))
+	})
+
+	It("should keep the versions pinned on its Stack when updating it", func() {
+		instance := newInstance()
+		allowVersions := allowGitops.DeepCopy()
+		allowVersions.Spec.Stacks[0].Overrides = map[string][]string{"argocd": {"version"}}
+		c := newFakeClient(teamNamespace.DeepCopy(), allowVersions, instance)
+		r := &StackInstanceReconciler{Client: c}
+
+		reconcileInstance(r, instance)
+		reconcileInstance(r, instance)
+
+		// pinned by the defaulting webhook on create
+		stk := &appv1.Stack{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, stk)).To(Succeed())
+		stk.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"namespace":"team-a","version":"v2.13.0"}}`)}
+		Expect(c.Update(ctx, stk)).To(Succeed())
+
+		overridesOf := func() map[string]map[string]any {
+			GinkgoHelper()
+			Expect(c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, stk)).To(Succeed())
+			overrides := map[string]map[string]any{}
+			Expect(json.Unmarshal(stk.Spec.Overrides.Raw, &overrides)).To(Succeed())
+			return overrides
+		}
+
+		reconcileInstance(r, instance)
+		Expect(overridesOf()["argocd"]).To(Equal(map[string]any{"namespace": "team-a", "version": "v2.13.0"}))
+
+		instance.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"version":"v2.14.0"}}`)}
+		Expect(c.Update(ctx, instance)).To(Succeed())
+		reconcileInstance(r, instance)
+		Expect(overridesOf()["argocd"]).To(HaveKeyWithValue("version", "v2.14.0"))
+	})
+
+	It("should keep the versions the stack pins besides the ones of its components", func() {
+		current := appv1.StackSpec{
+			StackName: "wasm/spinkube-standard",
+			Overrides: &apiextensionsv1.JSON{Raw: []byte(`{"kwasm-operator":{"shimVersion":"v0.15.1"}}`)},
+		}
+
+		spec := appv1.StackSpec{StackName: "wasm/spinkube-standard"}
+		Expect(keepPinnedVersions(&spec, current)).To(Succeed())
+		Expect(spec.Overrides).NotTo(BeNil())
+		Expect(string(spec.Overrides.Raw)).To(Equal(`{"kwasm-operator":{"shimVersion":"v0.15.1"}}`))
+
+		spec = appv1.StackSpec{StackName: "wasm/spinkube-standard", DisableComponents: []string{"kwasm-operator"}}
+		Expect(keepPinnedVersions(&spec, current)).To(Succeed())
+		Expect(spec.Overrides).To(BeNil())
+	})
+
+	It("should not install an instance the policies don't allow", func() {
+		instance := newInstance()
+		instance.Spec.DisableComponents = nil
+		c := newFakeClient(teamNamespace.DeepCopy(), allowGitops.DeepCopy(), instance)
+		r := &StackInstanceReconciler{Client: c}
+
+		reconcileInstance(r, instance)
+		reconcileInstance(r, instance)
+
+		Expect(instance.Status.StatusCode).To(Equal(appv1.Failure))
+		cond := meta.FindStatusCondition(instance.Status.Conditions, appv1.ConditionAdmitted)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(ReasonPolicyViolation))
+
+		err := c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, &appv1.Stack{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should delete its Stack before letting go of the instance", func() {
+		instance := newInstance()
+		c := newFakeClient(teamNamespace.DeepCopy(), allowGitops.DeepCopy(), instance)
+		r := &StackInstanceReconciler{Client: c}
+
+		reconcileInstance(r, instance)
+		reconcileInstance(r, instance)
+
+		stk := &appv1.Stack{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, stk)).To(Succeed())
+		stk.Finalizers = []string{managerFinalizer}
+		Expect(c.Update(ctx, stk)).To(Succeed())
+
+		Expect(c.Delete(ctx, instance)).To(Succeed())
+		reconcileInstance(r, instance)
+		Expect(instance.Finalizers).To(ContainElement(instanceFinalizer))
+		Expect(c.Get(ctx, client.ObjectKey{Name: "team-a.gitops"}, stk)).To(Succeed())
+		Expect(stk.DeletionTimestamp.IsZero()).To(BeFalse())
+
+		stk.Finalizers = nil
+		Expect(c.Update(ctx, stk)).To(Succeed())
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(instance)})
+		Expect(err).NotTo(HaveOccurred())
+		err = c.Get(ctx, client.ObjectKeyFromObject(instance), instance)
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should map a Stack back to its instance", func() {
+		stk := &appv1.Stack{ObjectMeta: metav1.ObjectMeta{Name: "team-a.gitops", Labels: map[string]string{
+			appv1.InstanceNamespaceLabel: "team-a",
+			appv1.InstanceNameLabel:      "gitops",
+		}}}
+		Expect(stackToInstance(ctx, stk)).To(HaveLen(1))
+		Expect(stackToInstance(ctx, stk)[0].NamespacedName.String()).To(Equal("team-a/gitops"))
+		Expect(stackToInstance(ctx, newStack("gitops", "gitops-standard"))).To(BeEmpty())
+	})
+})
@@ -2,102 +2,175 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
 
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1 "github.com/ksctl/ka/api/v1"
 )
 
+// StackState holds every installed Stack object keyed by its name, it is
+// shared by the workers so records are only handed out and stored as copies.
 type StackState struct {
+	mu     sync.RWMutex
 	Stacks map[string]AppState `json:"stacks"`
 }
 type AppState struct {
+	StackName  string                    `json:"stackName,omitempty"`
+	UID        types.UID                 `json:"uid,omitempty"`
 	Components map[string]ComponentState `json:"components"`
 }
 type ComponentState struct {
 	Ver string `json:"version"`
+	// Hash of the rendered component, see stacks.GetComponentHash
+	Hash string `json:"hash,omitempty"`
+	// Releases are the helm releases (namespace/name) owned by the component
+	Releases []string `json:"releases,omitempty"`
+	// Namespaces are the namespaces created by the component
+	Namespaces []string `json:"namespaces,omitempty"`
+	// WaitingReady is set from the deployment of the component until it passed
+	// its readiness check
+	WaitingReady bool `json:"waitingReady,omitempty"`
 }
 
-func getConfigmap() *corev1.ConfigMap {
-	return &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "ka-state",
-			Namespace: "ka-system",
-		},
-		BinaryData: map[string][]byte{},
+func (a AppState) clone() AppState {
+	a.Components = maps.Clone(a.Components)
+	if a.Components == nil {
+		a.Components = map[string]ComponentState{}
 	}
+	return a
 }
 
-func (r *StackReconciler) Save(ctx context.Context) error {
-	cf := &corev1.ConfigMap{}
-	if err := r.Get(ctx, client.ObjectKey{Namespace: "ka-system", Name: "ka-state"}, cf); err != nil {
-		if errors.IsNotFound(err) {
-			_cf := getConfigmap()
-			_cf.BinaryData["data"], err = json.Marshal(r.state)
-			if err != nil {
-				return err
-			}
-			if err := r.Create(ctx, _cf); err != nil {
-				return err
-			}
-			return nil
-		} else {
-			return err
-		}
+// get returns a copy of the record, changes only show once put back.
+func (s *StackState) get(key string) (AppState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	appState, ok := s.Stacks[key]
+	if !ok {
+		return AppState{}, false
 	}
+	return appState.clone(), true
+}
 
-	var err error
-	cf.BinaryData["data"], err = json.Marshal(r.state)
+func (s *StackState) put(key string, appState AppState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Stacks[key] = appState.clone()
+}
+
+func (s *StackState) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Stacks, key)
+}
+
+// Save persists the record of the Stack object, the record is dropped once
+// the stack got uninstalled.
+func (r *StackReconciler) Save(ctx context.Context, app *appv1.Stack) error {
+	if appState, ok := r.state.get(r.StateKey(app)); ok {
+		return r.Storage.Put(ctx, app, appState)
+	}
+	return r.Storage.Delete(ctx, app)
+}
+
+func (r *StackReconciler) Load(ctx context.Context) error {
+	stacks, err := r.Storage.List(ctx)
 	if err != nil {
 		return err
 	}
-	if err := r.Update(ctx, cf); err != nil {
+	state := &StackState{Stacks: stacks}
+	if err := r.migrateLegacyState(ctx, state); err != nil {
 		return err
 	}
+	r.state = state
 	return nil
 }
 
-func (r *StackReconciler) Load(ctx context.Context) error {
-	cf := &corev1.ConfigMap{}
-	if err := r.Get(ctx, client.ObjectKey{Namespace: "ka-system", Name: "ka-state"}, cf); err != nil {
-		if errors.IsNotFound(err) {
-			r.state = &StackState{Stacks: map[string]AppState{}}
-			return nil
-		}
+// migrateLegacyState moves the records of the single ka-state ConfigMap written
+// by earlier versions into the storage, each one is handed to the oldest Stack
+// object of its stackName.
+func (r *StackReconciler) migrateLegacyState(ctx context.Context, state *StackState) error {
+	l := log.FromContext(ctx)
+
+	cf, legacy, err := loadLegacyState(ctx, r.Client, r.stateNamespace())
+	if err != nil || cf == nil {
 		return err
 	}
-
-	if cf.BinaryData == nil {
-		r.state = &StackState{Stacks: map[string]AppState{}}
-	} else {
-		if _, ok := cf.BinaryData["data"]; !ok {
-			r.state = &StackState{Stacks: map[string]AppState{}}
-			return nil
+	for stackName, appState := range legacy.Stacks {
+		if _, ok := state.Stacks[stackName]; !ok {
+			state.Stacks[stackName] = appState
 		}
-		r.state = &StackState{Stacks: map[string]AppState{}}
-		if err := json.Unmarshal(cf.BinaryData["data"], r.state); err != nil {
+	}
+
+	stks := &appv1.StackList{}
+	if err := r.List(ctx, stks); err != nil {
+		return err
+	}
+	for _, app := range adoptLegacyRecords(state, stks.Items) {
+		if err := r.Storage.Put(ctx, app, state.Stacks[app.Name]); err != nil {
 			return err
 		}
 	}
-	return nil
-}
 
-func (r *StackReconciler) WasStackInstalled(stackName string) bool {
-	if _, ok := r.state.Stacks[stackName]; !ok {
-		return false
+	for stackName := range legacy.Stacks {
+		if appState, ok := state.Stacks[stackName]; ok && appState.UID == "" {
+			l.Info("Dropping state without a Stack object", "stack", stackName)
+			delete(state.Stacks, stackName)
+		}
 	}
-	return true
+
+	l.Info("Migrated legacy state", "configmap", client.ObjectKeyFromObject(cf))
+	return client.IgnoreNotFound(r.Delete(ctx, cf))
 }
 
-func (r *StackReconciler) WasComponentInstalled(stackName, componentName string) bool {
-	if !r.WasStackInstalled(stackName) {
-		return false
+// adoptLegacyRecords rekeys the records keyed by stackName, written before
+// Stack objects were tracked individually, to the oldest Stack object of that
+// stackName without a record of its own. It returns the Stack objects which
+// adopted one. The state is not shared yet, so it is changed in place.
+func adoptLegacyRecords(state *StackState, stks []appv1.Stack) []*appv1.Stack {
+	stks = slices.Clone(stks)
+	slices.SortStableFunc(stks, func(a, b appv1.Stack) int {
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+
+	var adopted []*appv1.Stack
+	for i := range stks {
+		app := &stks[i]
+		legacy, ok := state.Stacks[app.Spec.StackName]
+		if !ok || legacy.UID != "" {
+			continue
+		}
+		if own, ok := state.Stacks[app.Name]; ok && own.UID != "" {
+			continue
+		}
+		delete(state.Stacks, app.Spec.StackName)
+		legacy.StackName = app.Spec.StackName
+		legacy.UID = app.UID
+		state.Stacks[app.Name] = legacy
+		adopted = append(adopted, app)
 	}
+	return adopted
+}
+
+// StateKey returns the key the Stack object is recorded under.
+func (r *StackReconciler) StateKey(app *appv1.Stack) string {
+	return app.Name
+}
+
+func (r *StackReconciler) WasStackInstalled(key string) bool {
+	_, ok := r.state.get(key)
+	return ok
+}
 
-	if _, okC := r.state.Stacks[stackName].Components[componentName]; !okC {
+func (r *StackReconciler) WasComponentInstalled(key, componentName string) bool {
+	appState, ok := r.state.get(key)
+	if !ok {
 		return false
 	}
-	return true
+	_, ok = appState.Components[componentName]
+	return ok
 }
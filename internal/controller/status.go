@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+const (
+	ReasonReconciling      string = "Reconciling"
+	ReasonInstallSucceeded string = "InstallSucceeded"
+	ReasonInstallFailed    string = "InstallFailed"
+	ReasonUninstalling     string = "Uninstalling"
+	ReasonUninstallFailed  string = "UninstallFailed"
+	ReasonConflict         string = "ConflictingStack"
+	ReasonNoConflict       string = "NoConflict"
+	ReasonDriftDetected    string = "DriftDetected"
+	ReasonDriftHealed      string = "DriftHealed"
+	ReasonDriftCheckFailed string = "DriftCheckFailed"
+	ReasonNoDrift          string = "NoDrift"
+	ReasonRolledBack       string = "RolledBack"
+	ReasonRollbackFailed   string = "RollbackFailed"
+	ReasonPaused           string = "Paused"
+	ReasonRetriesExhausted string = "RetriesExhausted"
+	ReasonPlanReady        string = "PlanReady"
+	ReasonPlanFailed       string = "PlanFailed"
+	ReasonAwaitingApproval string = "AwaitingApproval"
+	ReasonPlanApproved     string = "PlanApproved"
+	ReasonUpdatesAvailable string = "UpdatesAvailable"
+	ReasonUpToDate         string = "UpToDate"
+)
+
+// updateComponentStatus finds (or appends) the status entry of the component and
+// applies fn to it, bumping the transition time whenever the phase changes.
+func updateComponentStatus(app *appv1.Stack, id stack.ComponentID, fn func(*appv1.ComponentStatus)) {
+	idx := -1
+	for i := range app.Status.Components {
+		if app.Status.Components[i].ID == string(id) {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		app.Status.Components = append(app.Status.Components, appv1.ComponentStatus{
+			ID:    string(id),
+			Phase: appv1.ComponentPending,
+		})
+		idx = len(app.Status.Components) - 1
+	}
+
+	c := &app.Status.Components[idx]
+	prevPhase := c.Phase
+	fn(c)
+	if c.Phase != prevPhase || c.LastTransitionTime.IsZero() {
+		c.LastTransitionTime = metav1.Now()
+	}
+}
+
+func setComponentPhase(
+	app *appv1.Stack,
+	id stack.ComponentID,
+	component stack.Component,
+	desiredVer string,
+	phase appv1.ComponentPhase,
+	err error,
+) {
+	updateComponentStatus(app, id, func(c *appv1.ComponentStatus) {
+		c.HandlerType = string(component.HandlerType)
+		c.VersionConstraint = componentVersionConstraint(app, id)
+		c.DesiredVersion = desiredVer
+		c.Phase = phase
+		c.LastError = ""
+		if err != nil {
+			c.LastError = err.Error()
+		}
+		switch phase {
+		case appv1.ComponentInstalled:
+			c.InstalledVersion = desiredVer
+		case appv1.ComponentUninstalled, appv1.ComponentDisabled:
+			c.InstalledVersion = ""
+		}
+	})
+}
+
+// componentVersionConstraint returns the semver constraint the Stack resolves
+// the version of the component with, if any.
+func componentVersionConstraint(app *appv1.Stack, id stack.ComponentID) string {
+	overrides, err := getStackOverrides(app)
+	if err != nil {
+		return ""
+	}
+	return versionConstraint(overrides, id)
+}
+
+func setStackCondition(app *appv1.Stack, condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: app.Generation,
+	})
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+var _ = Describe("Stack status helpers", func() {
+	It("should track a component through its phases", func() {
+		app := &appv1.Stack{}
+		component := stack.Component{HandlerType: stack.ComponentTypeHelm}
+
+		setComponentPhase(app, "istio", component, "1.22.4", appv1.ComponentInstalling, nil)
+		Expect(app.Status.Components).To(HaveLen(1))
+		Expect(app.Status.Components[0].Phase).To(Equal(appv1.ComponentInstalling))
+		Expect(app.Status.Components[0].HandlerType).To(Equal(string(stack.ComponentTypeHelm)))
+		Expect(app.Status.Components[0].LastTransitionTime.IsZero()).To(BeFalse())
+
+		setComponentPhase(app, "istio", component, "1.22.4", appv1.ComponentFailed, errors.New("boom"))
+		Expect(app.Status.Components).To(HaveLen(1))
+		Expect(app.Status.Components[0].LastError).To(Equal("boom"))
+		Expect(app.Status.Components[0].InstalledVersion).To(BeEmpty())
+
+		setComponentPhase(app, "istio", component, "1.22.4", appv1.ComponentInstalled, nil)
+		Expect(app.Status.Components[0].LastError).To(BeEmpty())
+		Expect(app.Status.Components[0].InstalledVersion).To(Equal("1.22.4"))
+	})
+
+	It("should keep one condition per type", func() {
+		app := &appv1.Stack{}
+		setStackCondition(app, appv1.ConditionReady, metav1.ConditionFalse, ReasonInstallFailed, "boom")
+		setStackCondition(app, appv1.ConditionReady, metav1.ConditionTrue, ReasonInstallSucceeded, "ok")
+
+		Expect(app.Status.Conditions).To(HaveLen(1))
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, appv1.ConditionReady)).To(BeTrue())
+	})
+})
@@ -0,0 +1,270 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+type StorageKind string
+
+const (
+	StorageConfigMap StorageKind = "configmap"
+	StorageSecret    StorageKind = "secret"
+	StorageStatus    StorageKind = "status"
+)
+
+const (
+	DefaultStateNamespace string = "ka-system"
+
+	stateLabel      string = "app.ksctl.com/state"
+	stateAnnotation string = "app.ksctl.com/stack"
+	stateDataKey    string = "data"
+)
+
+// Storage persists the installation record of every Stack object, each Stack
+// object owns its record so writes for different stacks never collide.
+type Storage interface {
+	// List returns the records of every Stack object keyed by the Stack name.
+	List(ctx context.Context) (map[string]AppState, error)
+	Put(ctx context.Context, app *appv1.Stack, state AppState) error
+	Delete(ctx context.Context, app *appv1.Stack) error
+}
+
+// NewStorage returns the storage backend of the given kind, the object based
+// backends keep their records in namespace.
+func NewStorage(kind StorageKind, c client.Client, namespace string) (Storage, error) {
+	switch kind {
+	case StorageConfigMap:
+		return NewConfigMapStorage(c, namespace), nil
+	case StorageSecret:
+		return NewSecretStorage(c, namespace), nil
+	case StorageStatus:
+		return NewStatusStorage(c), nil
+	}
+	return nil, fmt.Errorf("unknown state storage %q, expected one of %s, %s, %s",
+		kind, StorageConfigMap, StorageSecret, StorageStatus)
+}
+
+// objectStorage keeps one object per Stack in a namespace, the ConfigMap and
+// Secret backends only differ in how the record is attached to the object.
+type objectStorage struct {
+	client    client.Client
+	namespace string
+
+	newObject func() client.Object
+	newList   func() client.ObjectList
+	items     func(client.ObjectList) []client.Object
+	getData   func(client.Object) []byte
+	setData   func(client.Object, []byte)
+}
+
+func NewConfigMapStorage(c client.Client, namespace string) Storage {
+	return &objectStorage{
+		client:    c,
+		namespace: namespace,
+		newObject: func() client.Object { return &corev1.ConfigMap{} },
+		newList:   func() client.ObjectList { return &corev1.ConfigMapList{} },
+		items: func(l client.ObjectList) []client.Object {
+			var objs []client.Object
+			for i := range l.(*corev1.ConfigMapList).Items {
+				objs = append(objs, &l.(*corev1.ConfigMapList).Items[i])
+			}
+			return objs
+		},
+		getData: func(o client.Object) []byte { return o.(*corev1.ConfigMap).BinaryData[stateDataKey] },
+		setData: func(o client.Object, data []byte) {
+			cm := o.(*corev1.ConfigMap)
+			if cm.BinaryData == nil {
+				cm.BinaryData = map[string][]byte{}
+			}
+			cm.BinaryData[stateDataKey] = data
+		},
+	}
+}
+
+func NewSecretStorage(c client.Client, namespace string) Storage {
+	return &objectStorage{
+		client:    c,
+		namespace: namespace,
+		newObject: func() client.Object { return &corev1.Secret{Type: corev1.SecretTypeOpaque} },
+		newList:   func() client.ObjectList { return &corev1.SecretList{} },
+		items: func(l client.ObjectList) []client.Object {
+			var objs []client.Object
+			for i := range l.(*corev1.SecretList).Items {
+				objs = append(objs, &l.(*corev1.SecretList).Items[i])
+			}
+			return objs
+		},
+		getData: func(o client.Object) []byte { return o.(*corev1.Secret).Data[stateDataKey] },
+		setData: func(o client.Object, data []byte) {
+			secret := o.(*corev1.Secret)
+			if secret.Data == nil {
+				secret.Data = map[string][]byte{}
+			}
+			secret.Data[stateDataKey] = data
+		},
+	}
+}
+
+func stateObjectName(app *appv1.Stack) string {
+	return "ka-state-" + app.Name
+}
+
+func (s *objectStorage) List(ctx context.Context) (map[string]AppState, error) {
+	list := s.newList()
+	if err := s.client.List(ctx, list,
+		client.InNamespace(s.namespace),
+		client.MatchingLabels{stateLabel: "true"},
+	); err != nil {
+		return nil, err
+	}
+
+	stacks := map[string]AppState{}
+	for _, o := range s.items(list) {
+		key, ok := o.GetAnnotations()[stateAnnotation]
+		if !ok {
+			continue
+		}
+		var state AppState
+		if err := json.Unmarshal(s.getData(o), &state); err != nil {
+			return nil, fmt.Errorf("state of stack %s: %w", key, err)
+		}
+		stacks[key] = state
+	}
+	return stacks, nil
+}
+
+func (s *objectStorage) Put(ctx context.Context, app *appv1.Stack, state AppState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		o := s.newObject()
+		err := s.client.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: stateObjectName(app)}, o)
+		if errors.IsNotFound(err) {
+			o = s.newObject()
+			o.SetName(stateObjectName(app))
+			o.SetNamespace(s.namespace)
+			o.SetLabels(map[string]string{stateLabel: "true"})
+			o.SetAnnotations(map[string]string{stateAnnotation: app.Name})
+			s.setData(o, data)
+			err = s.client.Create(ctx, o)
+			if errors.IsAlreadyExists(err) {
+				// lost the race with another writer, treat it like a conflicting update
+				return errors.NewConflict(corev1.Resource("state"), o.GetName(), err)
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		s.setData(o, data)
+		// the resourceVersion from the Get guards the Update
+		return s.client.Update(ctx, o)
+	})
+}
+
+func (s *objectStorage) Delete(ctx context.Context, app *appv1.Stack) error {
+	o := s.newObject()
+	o.SetName(stateObjectName(app))
+	o.SetNamespace(s.namespace)
+	return client.IgnoreNotFound(s.client.Delete(ctx, o))
+}
+
+// statusStorage keeps the record in the status of the Stack object itself.
+type statusStorage struct {
+	client client.Client
+}
+
+func NewStatusStorage(c client.Client) Storage {
+	return &statusStorage{client: c}
+}
+
+func (s *statusStorage) List(ctx context.Context) (map[string]AppState, error) {
+	list := &appv1.StackList{}
+	if err := s.client.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	stacks := map[string]AppState{}
+	for _, stk := range list.Items {
+		if stk.Status.State == nil {
+			continue
+		}
+		var state AppState
+		if err := json.Unmarshal(stk.Status.State.Raw, &state); err != nil {
+			return nil, fmt.Errorf("state of stack %s: %w", stk.Name, err)
+		}
+		stacks[stk.Name] = state
+	}
+	return stacks, nil
+}
+
+func (s *statusStorage) Put(ctx context.Context, app *appv1.Stack, state AppState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, app, &apiextensionsv1.JSON{Raw: data})
+}
+
+func (s *statusStorage) Delete(ctx context.Context, app *appv1.Stack) error {
+	return s.update(ctx, app, nil)
+}
+
+// update writes the record along with the rest of the status held by app, on
+// a conflict the record is applied to the latest object and app picks up the
+// new resourceVersion so the reconciler can keep updating it.
+func (s *statusStorage) update(ctx context.Context, app *appv1.Stack, state *apiextensionsv1.JSON) error {
+	app.Status.State = state
+	if err := s.client.Status().Update(ctx, app); !errors.IsConflict(err) {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &appv1.Stack{}
+		if err := s.client.Get(ctx, client.ObjectKeyFromObject(app), latest); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		latest.Status.State = state
+		if err := s.client.Status().Update(ctx, latest); err != nil {
+			return err
+		}
+		app.ResourceVersion = latest.ResourceVersion
+		return nil
+	})
+}
+
+// loadLegacyState reads the state written by earlier versions of the manager,
+// a single ConfigMap holding every stack keyed by stackName.
+func loadLegacyState(ctx context.Context, c client.Client, namespace string) (*corev1.ConfigMap, *StackState, error) {
+	cf := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "ka-state"}, cf); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	state := &StackState{Stacks: map[string]AppState{}}
+	if data, ok := cf.BinaryData[stateDataKey]; ok {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cf, state, nil
+}
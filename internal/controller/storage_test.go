@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+func newFakeClient(objs ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+	Expect(appv1.AddToScheme(s)).To(Succeed())
+	return fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(objs...).
+		WithStatusSubresource(&appv1.Stack{}, &appv1.StackInstance{}).
+		Build()
+}
+
+func newStack(name, stackName string) *appv1.Stack {
+	return &appv1.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("uid-" + name)},
+		Spec:       appv1.StackSpec{StackName: stackName},
+	}
+}
+
+var _ = Describe("State storage", func() {
+	ctx := context.Background()
+	appState := AppState{
+		StackName:  "monitoring-lite",
+		Components: map[string]ComponentState{"kube-prometheus": {Ver: "72.6.2"}},
+	}
+
+	for _, kind := range []StorageKind{StorageConfigMap, StorageSecret, StorageStatus} {
+		It("should keep one record per Stack with the "+string(kind)+" backend", func() {
+			a, b := newStack("monitoring-a", "monitoring-lite"), newStack("monitoring-b", "monitoring-lite")
+			c := newFakeClient(a, b)
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(a), a)).To(Succeed())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(b), b)).To(Succeed())
+
+			storage, err := NewStorage(kind, c, "ka-system")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(storage.Put(ctx, a, appState)).To(Succeed())
+			Expect(storage.Put(ctx, b, appState)).To(Succeed())
+			// a second write goes through an update
+			Expect(storage.Put(ctx, a, AppState{StackName: "monitoring-lite"})).To(Succeed())
+
+			stacks, err := storage.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stacks).To(HaveLen(2))
+			Expect(stacks["monitoring-a"].Components).To(BeEmpty())
+			Expect(stacks["monitoring-b"]).To(Equal(appState))
+
+			Expect(storage.Delete(ctx, a)).To(Succeed())
+			Expect(storage.Delete(ctx, a)).To(Succeed())
+			stacks, err = storage.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stacks).To(HaveKey("monitoring-b"))
+			Expect(stacks).NotTo(HaveKey("monitoring-a"))
+		})
+	}
+
+	It("should reject unknown backends", func() {
+		_, err := NewStorage("etcd", newFakeClient(), "ka-system")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should migrate the legacy ConfigMap to the oldest Stack of the stackName", func() {
+		data, err := json.Marshal(StackState{Stacks: map[string]AppState{
+			"monitoring-lite": appState,
+			"gitops-standard": {Components: map[string]ComponentState{"argocd": {Ver: "v2.13.0"}}},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+
+		older, newer := newStack("monitoring-a", "monitoring-lite"), newStack("monitoring-b", "monitoring-lite")
+		older.CreationTimestamp = metav1.Unix(100, 0)
+		newer.CreationTimestamp = metav1.Unix(200, 0)
+		c := newFakeClient(older, newer, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ka-state", Namespace: "ka-system"},
+			BinaryData: map[string][]byte{"data": data},
+		})
+
+		r := &StackReconciler{Client: c}
+		Expect(r.InitializeStorage(ctx)).To(Succeed())
+
+		Expect(r.state.Stacks).To(HaveLen(1))
+		Expect(r.state.Stacks["monitoring-a"].UID).To(BeEquivalentTo("uid-monitoring-a"))
+
+		stacks, err := r.Storage.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stacks).To(HaveKey("monitoring-a"))
+
+		err = c.Get(ctx, client.ObjectKey{Namespace: "ka-system", Name: "ka-state"}, &corev1.ConfigMap{})
+		Expect(client.IgnoreNotFound(err)).To(Succeed())
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,268 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/schedule"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+)
+
+// DefaultUpdateCheckInterval is how often the version sources get checked for
+// updates of Stacks not setting spec.updates.checkInterval.
+const DefaultUpdateCheckInterval = time.Hour
+
+func updateCheckInterval(app *appv1.Stack) time.Duration {
+	if u := app.Spec.Updates; u != nil && u.CheckInterval != nil && u.CheckInterval.Duration > 0 {
+		return u.CheckInterval.Duration
+	}
+	return DefaultUpdateCheckInterval
+}
+
+// maintenanceWindow returns the window the upgrades wait for, nil when they
+// don't wait.
+func maintenanceWindow(app *appv1.Stack) (*schedule.Window, error) {
+	if app.Spec.Updates == nil || app.Spec.Updates.MaintenanceWindow == nil {
+		return nil, nil
+	}
+	w := app.Spec.Updates.MaintenanceWindow
+	return schedule.NewWindow(w.Schedule, w.Duration.Duration, w.TimeZone)
+}
+
+// followsChannel tells whether the component is upgraded along its update
+// channel, which only happens while its version override leaves it floating.
+func followsChannel(app *appv1.Stack, overrides map[string]map[string]any, componentId stack.ComponentID) bool {
+	if app.Spec.Updates.ChannelOf(string(componentId)) == appv1.ChannelPinned {
+		return false
+	}
+	if slices.Contains(app.Spec.DisableComponents, string(componentId)) {
+		return false
+	}
+	// components without a version source can't resolve the channel constraint
+	if components, ok := stacks.GetComponentOverridings(app.Spec.StackName); !ok || !components[componentId].ResolvesVersions() {
+		return false
+	}
+	return !isVersionPinned(overrides, componentId)
+}
+
+// channelConstraint is the version constraint of the releases on the channel
+// of the installed version, empty when that is not a semantic version.
+func channelConstraint(channel appv1.UpdateChannel, installed string) string {
+	v, err := semver.NewVersion(installed)
+	if err != nil {
+		return ""
+	}
+	switch channel {
+	case appv1.ChannelPatch:
+		return "~" + v.String()
+	case appv1.ChannelMinor:
+		// caret keeps 0.x on its minor version
+		return "^" + v.String()
+	}
+	return ""
+}
+
+func isNewerVersion(ver, than string) bool {
+	v, err := semver.NewVersion(ver)
+	if err != nil {
+		return false
+	}
+	t, err := semver.NewVersion(than)
+	if err != nil {
+		return false
+	}
+	return v.GreaterThan(t)
+}
+
+// checkUpdates lists the newest release on the channel of every installed
+// component following one into status, once per check interval. A failed
+// check keeps the updates found before.
+func (r *StackReconciler) checkUpdates(ctx context.Context, app *appv1.Stack, now time.Time) {
+	l := log.FromContext(ctx)
+
+	if app.Spec.Updates == nil {
+		app.Status.Updates = nil
+		for i := range app.Status.Components {
+			app.Status.Components[i].AvailableVersion = ""
+		}
+		meta.RemoveStatusCondition(&app.Status.Conditions, appv1.ConditionUpdatesAvailable)
+		return
+	}
+	if app.Status.Updates == nil {
+		app.Status.Updates = &appv1.UpdateStatus{}
+	}
+	if last := app.Status.Updates.LastCheckTime; last != nil && now.Sub(last.Time) < updateCheckInterval(app) {
+		return
+	}
+
+	err := r.listUpdates(ctx, app)
+	app.Status.Updates.LastCheckTime = &metav1.Time{Time: now}
+	app.Status.Updates.LastError = ""
+	if err != nil {
+		l.Error(err, "Failed to check for updates", "stack", app.Spec.StackName)
+		app.Status.Updates.LastError = err.Error()
+		r.event(app, corev1.EventTypeWarning, EventUpdateCheckFailed, "Failed to check for updates: %v", err)
+	}
+}
+
+func (r *StackReconciler) listUpdates(ctx context.Context, app *appv1.Stack) error {
+	kl := logger.NewStructuredLogger(-1, os.Stdout)
+
+	appState, ok := r.state.get(r.StateKey(app))
+	if !ok {
+		return nil
+	}
+	overrides, err := getStackOverrides(app)
+	if err != nil {
+		return err
+	}
+
+	// rendering the stack with the channels as constraints resolves the
+	// newest release on them, the way an override like "~1.22" would
+	desired := getDesiredOverrides(overrides, appState)
+	following := map[stack.ComponentID]string{}
+	for id, componentState := range appState.Components {
+		componentId := stack.ComponentID(id)
+		if !followsChannel(app, overrides, componentId) {
+			continue
+		}
+		constraint := channelConstraint(app.Spec.Updates.ChannelOf(id), componentState.Ver)
+		if len(constraint) == 0 {
+			continue
+		}
+		if desired[id] == nil {
+			desired[id] = map[string]any{}
+		}
+		desired[id]["version"] = constraint
+		following[componentId] = componentState.Ver
+	}
+
+	var manifest stack.ApplicationStack
+	if len(following) != 0 {
+		if manifest, err = getStackManifest(kl, app.Spec.StackName, desired); err != nil {
+			return err
+		}
+	}
+
+	for i := range app.Status.Components {
+		c := &app.Status.Components[i]
+		installed, ok := following[stack.ComponentID(c.ID)]
+		if !ok {
+			c.AvailableVersion = ""
+			continue
+		}
+		available := ""
+		if v, ok := manifest.Components[stack.ComponentID(c.ID)]; ok {
+			if ver := stacks.GetComponentVersionOverriding(v); isNewerVersion(ver, installed) {
+				available = ver
+			}
+		}
+		if len(available) != 0 && available != c.AvailableVersion {
+			r.event(app, corev1.EventTypeNormal, EventUpdateAvailable,
+				"Component %s version %s is available, %s is installed", c.ID, available, installed)
+		}
+		c.AvailableVersion = available
+	}
+	return nil
+}
+
+// dueUpdates returns the versions the components get upgraded to right now,
+// nothing while the maintenance window is closed.
+func dueUpdates(app *appv1.Stack, overrides map[string]map[string]any, appState AppState, now time.Time) (map[string]string, error) {
+	if app.Spec.Updates == nil {
+		return nil, nil
+	}
+	window, err := maintenanceWindow(app)
+	if err != nil {
+		return nil, err
+	}
+	if window != nil {
+		if open, _ := window.Open(now); !open {
+			return nil, nil
+		}
+	}
+
+	due := map[string]string{}
+	for _, c := range app.Status.Components {
+		componentState, ok := appState.Components[c.ID]
+		if !ok || len(c.AvailableVersion) == 0 {
+			continue
+		}
+		if !followsChannel(app, overrides, stack.ComponentID(c.ID)) || !isNewerVersion(c.AvailableVersion, componentState.Ver) {
+			continue
+		}
+		due[c.ID] = c.AvailableVersion
+	}
+	return due, nil
+}
+
+// recordUpdates drops the updates which got applied and tells whether the
+// others wait for the maintenance window, returning when it opens next.
+func recordUpdates(app *appv1.Stack, now time.Time) time.Time {
+	if app.Spec.Updates == nil || app.Status.Updates == nil {
+		return time.Time{}
+	}
+
+	var waiting []string
+	for i := range app.Status.Components {
+		c := &app.Status.Components[i]
+		if len(c.AvailableVersion) == 0 {
+			continue
+		}
+		if !isNewerVersion(c.AvailableVersion, c.InstalledVersion) {
+			c.AvailableVersion = ""
+			continue
+		}
+		waiting = append(waiting, fmt.Sprintf("%s %s", c.ID, c.AvailableVersion))
+	}
+
+	app.Status.Updates.NextWindowTime = nil
+	if len(waiting) == 0 {
+		setStackCondition(app, appv1.ConditionUpdatesAvailable, metav1.ConditionFalse, ReasonUpToDate,
+			"The components following an update channel are up to date")
+		return time.Time{}
+	}
+
+	var next time.Time
+	if window, err := maintenanceWindow(app); err == nil && window != nil {
+		if open, _ := window.Open(now); !open {
+			next = window.Next(now)
+		}
+	}
+	message := "Waiting to be upgraded: " + strings.Join(waiting, ", ")
+	if !next.IsZero() {
+		app.Status.Updates.NextWindowTime = &metav1.Time{Time: next}
+		message += ", the maintenance window opens at " + next.Format(time.RFC3339)
+	}
+	setStackCondition(app, appv1.ConditionUpdatesAvailable, metav1.ConditionTrue, ReasonUpdatesAvailable, message)
+	return next
+}
+
+// nextUpdateIn is how long until the updates need another look: the next check
+// or the maintenance window opening for the updates waiting on it.
+func nextUpdateIn(app *appv1.Stack, next time.Time, now time.Time) time.Duration {
+	if app.Spec.Updates == nil || app.Status.Updates == nil {
+		return 0
+	}
+	wait := updateCheckInterval(app)
+	if last := app.Status.Updates.LastCheckTime; last != nil {
+		wait = max(last.Add(updateCheckInterval(app)).Sub(now), time.Second)
+	}
+	if !next.IsZero() {
+		wait = min(wait, max(next.Sub(now), time.Second))
+	}
+	return wait
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/poller"
+)
+
+var _ = Describe("Stack updates", func() {
+	// a saturday
+	saturday := time.Date(2026, time.October, 17, 3, 0, 0, 0, time.UTC)
+	friday := saturday.Add(-15 * time.Hour)
+
+	newMesh := func(updates *appv1.UpdatePolicy) (*appv1.Stack, AppState) {
+		app := newStack("istio", "mesh-standard")
+		app.Spec.Updates = updates
+		app.Status.Components = []appv1.ComponentStatus{{ID: "istio", InstalledVersion: "1.22.3"}}
+		return app, AppState{
+			StackName:  "mesh-standard",
+			Components: map[string]ComponentState{"istio": {Ver: "1.22.3"}},
+		}
+	}
+
+	It("should only follow the channel of components left floating", func() {
+		app, _ := newMesh(&appv1.UpdatePolicy{Channels: map[string]appv1.UpdateChannel{"istio": appv1.ChannelPatch}})
+		Expect(followsChannel(app, nil, "istio")).To(BeTrue())
+		Expect(followsChannel(app, map[string]map[string]any{"istio": {"version": "latest"}}, "istio")).To(BeTrue())
+		Expect(followsChannel(app, map[string]map[string]any{"istio": {"version": "1.22.3"}}, "istio")).To(BeFalse())
+		Expect(followsChannel(app, nil, "istio-base")).To(BeFalse(), "the stack channel defaults to pinned")
+
+		monitoring := newStack("monitoring", "monitoring-lite")
+		monitoring.Spec.Updates = &appv1.UpdatePolicy{Channel: appv1.ChannelMinor}
+		Expect(followsChannel(monitoring, nil, "kube-prometheus")).To(BeFalse(), "no version source to follow")
+
+		app.Spec.DisableComponents = []string{"istio"}
+		Expect(followsChannel(app, nil, "istio")).To(BeFalse())
+
+		app.Spec.Updates = nil
+		Expect(followsChannel(app, nil, "istio")).To(BeFalse())
+	})
+
+	It("should keep the channels on the series of the installed version", func() {
+		Expect(channelConstraint(appv1.ChannelPatch, "1.22.3")).To(Equal("~1.22.3"))
+		Expect(channelConstraint(appv1.ChannelMinor, "v1.7.2")).To(Equal("^1.7.2"))
+		Expect(channelConstraint(appv1.ChannelPinned, "1.22.3")).To(BeEmpty())
+		Expect(channelConstraint(appv1.ChannelPatch, "stable")).To(BeEmpty())
+
+		Expect(isNewerVersion("v1.7.3", "1.7.2")).To(BeTrue())
+		Expect(isNewerVersion("1.7.2", "1.7.2")).To(BeFalse())
+		Expect(isNewerVersion("stable", "1.7.2")).To(BeFalse())
+	})
+
+	It("should list the newest release on the channel once per check interval", func() {
+		poller.InitSharedGithubReleaseFakePoller(func(org, repo string) ([]string, error) {
+			return []string{"1.23.0", "1.22.5", "1.22.4", "1.22.3"}, nil
+		})
+
+		app, appState := newMesh(&appv1.UpdatePolicy{Channels: map[string]appv1.UpdateChannel{"istio": appv1.ChannelPatch}})
+		r := &StackReconciler{state: &StackState{Stacks: map[string]AppState{"istio": appState}}}
+
+		r.checkUpdates(context.Background(), app, friday)
+		Expect(app.Status.Updates.LastError).To(BeEmpty())
+		Expect(app.Status.Updates.LastCheckTime.Time).To(Equal(friday))
+		Expect(app.Status.Components[0].AvailableVersion).To(Equal("1.22.5"))
+
+		app.Spec.Updates.Channels["istio"] = appv1.ChannelMinor
+		r.checkUpdates(context.Background(), app, friday.Add(time.Minute))
+		Expect(app.Status.Components[0].AvailableVersion).To(Equal("1.22.5"), "not checked again before the interval")
+
+		r.checkUpdates(context.Background(), app, friday.Add(DefaultUpdateCheckInterval))
+		Expect(app.Status.Components[0].AvailableVersion).To(Equal("1.23.0"))
+
+		app.Spec.Updates = nil
+		r.checkUpdates(context.Background(), app, friday.Add(2*DefaultUpdateCheckInterval))
+		Expect(app.Status.Updates).To(BeNil())
+		Expect(app.Status.Components[0].AvailableVersion).To(BeEmpty())
+	})
+
+	It("should only upgrade while the maintenance window is open", func() {
+		app, appState := newMesh(&appv1.UpdatePolicy{
+			Channel: appv1.ChannelPatch,
+			MaintenanceWindow: &appv1.MaintenanceWindow{
+				Schedule: "0 2 * * sat",
+				Duration: metav1.Duration{Duration: 3 * time.Hour},
+			},
+		})
+		app.Status.Updates = &appv1.UpdateStatus{LastCheckTime: &metav1.Time{Time: friday}}
+		app.Status.Components[0].AvailableVersion = "1.22.5"
+
+		due, err := dueUpdates(app, nil, appState, friday)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(due).To(BeEmpty())
+
+		next := recordUpdates(app, friday)
+		Expect(next).To(Equal(time.Date(2026, time.October, 17, 2, 0, 0, 0, time.UTC)))
+		Expect(app.Status.Updates.NextWindowTime.Time).To(Equal(next))
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, appv1.ConditionUpdatesAvailable)).To(BeTrue())
+		Expect(nextUpdateIn(app, next, friday)).To(Equal(DefaultUpdateCheckInterval))
+		Expect(nextUpdateIn(app, next, friday.Add(45*time.Minute))).To(Equal(15 * time.Minute))
+
+		due, err = dueUpdates(app, nil, appState, saturday)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(due).To(Equal(map[string]string{"istio": "1.22.5"}))
+
+		due, err = dueUpdates(app, map[string]map[string]any{"istio": {"version": "1.22.3"}}, appState, saturday)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(due).To(BeEmpty(), "pinned by the user")
+	})
+
+	It("should drop the updates once they are installed", func() {
+		app, _ := newMesh(&appv1.UpdatePolicy{Channel: appv1.ChannelPatch})
+		app.Status.Updates = &appv1.UpdateStatus{}
+		app.Status.Components[0].InstalledVersion = "1.22.5"
+		app.Status.Components[0].AvailableVersion = "1.22.5"
+
+		Expect(recordUpdates(app, saturday)).To(BeZero())
+		Expect(app.Status.Components[0].AvailableVersion).To(BeEmpty())
+		Expect(app.Status.Updates.NextWindowTime).To(BeNil())
+		cond := meta.FindStatusCondition(app.Status.Conditions, appv1.ConditionUpdatesAvailable)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(ReasonUpToDate))
+	})
+})
@@ -0,0 +1,150 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	ksctlHelm "github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/ksctl/ksctl/v2/pkg/k8s"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HelmDriftHandler returns what no longer matches the releases of the app, a
+// release is expected to exist and be deployed.
+func HelmDriftHandler(ctx context.Context, c *rest.Config, app *ksctlHelm.App) ([]string, error) {
+	var drift []string
+
+	for _, chart := range app.Charts {
+		cfg, err := newHelmActionConfig(ctx, c, chart.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		rel, err := action.NewStatus(cfg).Run(chart.ReleaseName)
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			drift = append(drift, fmt.Sprintf("helm release %s/%s not found", chart.Namespace, chart.ReleaseName))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if rel.Info == nil || rel.Info.Status != release.StatusDeployed {
+			status := release.StatusUnknown
+			if rel.Info != nil {
+				status = rel.Info.Status
+			}
+			drift = append(drift, fmt.Sprintf("helm release %s/%s is %s", chart.Namespace, chart.ReleaseName, status))
+		}
+	}
+	return drift, nil
+}
+
+// K8sDriftHandler returns the objects of the app manifests which are missing
+// from the cluster.
+func K8sDriftHandler(ctx context.Context, c *rest.Config, app *k8s.App) ([]string, error) {
+	cl, err := client.New(c, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []string
+	for _, url := range app.Urls {
+		objs, err := fetchManifestObjects(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objs {
+			namespaced, err := cl.IsObjectNamespaced(obj)
+			if meta.IsNoMatchError(err) {
+				// the kind itself is gone, e.g. its CRD got removed
+				drift = append(drift, fmt.Sprintf("%s %s: kind not served", obj.GetKind(), obj.GetName()))
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			key := client.ObjectKey{Name: obj.GetName()}
+			if namespaced {
+				key.Namespace = obj.GetNamespace()
+				if len(key.Namespace) == 0 {
+					key.Namespace = app.Namespace
+				}
+				if len(key.Namespace) == 0 {
+					key.Namespace = "default"
+				}
+			}
+
+			found := &unstructured.Unstructured{}
+			found.SetGroupVersionKind(obj.GroupVersionKind())
+			if err := cl.Get(ctx, key, found); apierrors.IsNotFound(err) {
+				drift = append(drift, fmt.Sprintf("%s %s not found", obj.GetKind(), key))
+			} else if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return drift, nil
+}
+
+func fetchManifestObjects(ctx context.Context, url string) ([]*unstructured.Unstructured, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	objs, err := decodeManifestObjects(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", url, err)
+	}
+	return objs, nil
+}
+
+// decodeManifestObjects reads the objects of a multi-document YAML (or JSON)
+// manifest, Lists get flattened into their items.
+func decodeManifestObjects(r io.Reader) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue // empty document
+		}
+		if obj.IsList() {
+			if err := obj.EachListItem(func(o runtime.Object) error {
+				objs = append(objs, o.(*unstructured.Unstructured))
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const manifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: argocd
+---
+# only a comment
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: argocd-server
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: argocd-server
+`
+
+func TestFetchManifestObjects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/install.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(manifest))
+	}))
+	defer srv.Close()
+
+	objs, err := fetchManifestObjects(context.Background(), srv.URL+"/install.yaml")
+	assert.Nil(t, err)
+	if assert.Len(t, objs, 3) {
+		assert.Equal(t, "Namespace", objs[0].GetKind())
+		assert.Equal(t, "Deployment", objs[1].GetKind())
+		assert.Equal(t, "argocd-server", objs[1].GetName())
+		assert.Equal(t, "ServiceAccount", objs[2].GetKind())
+	}
+
+	_, err = fetchManifestObjects(context.Background(), srv.URL+"/missing.yaml")
+	assert.ErrorContains(t, err, "404")
+}
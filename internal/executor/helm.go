@@ -2,14 +2,35 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/ksctl/ksctl/v2/pkg/consts"
 	ksctlHelm "github.com/ksctl/ksctl/v2/pkg/helm"
 	"github.com/ksctl/ksctl/v2/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/rest"
+
+	"github.com/ksctl/ka/internal/tracing"
 )
 
-func HelmDeployHandler(ctx context.Context, app *ksctlHelm.App) error {
+// helmClientMu has the ksctl helm clients take turns. Each of them adds the
+// repository of its app to the repositories.yaml they all share, reading and
+// writing it back without a lock, so the helm components of a dependency level
+// deploying at once would drop each other's repositories.
+var helmClientMu sync.Mutex
+
+func HelmDeployHandler(ctx context.Context, app *ksctlHelm.App) (err error) {
+	ctx, span := tracing.Start(ctx, "helm.deploy", helmAttributes(app)...)
+	defer func() { tracing.End(span, err) }()
+
+	helmClientMu.Lock()
+	defer helmClientMu.Unlock()
+
 	helmOption := []ksctlHelm.Option{
 		ksctlHelm.WithDebug(),
 	}
@@ -33,7 +54,13 @@ func HelmDeployHandler(ctx context.Context, app *ksctlHelm.App) error {
 	return nil
 }
 
-func HelmUninstallHandler(ctx context.Context, app *ksctlHelm.App) error {
+func HelmUninstallHandler(ctx context.Context, app *ksctlHelm.App) (err error) {
+	ctx, span := tracing.Start(ctx, "helm.uninstall", helmAttributes(app)...)
+	defer func() { tracing.End(span, err) }()
+
+	helmClientMu.Lock()
+	defer helmClientMu.Unlock()
+
 	helmOption := []ksctlHelm.Option{
 		ksctlHelm.WithDebug(),
 	}
@@ -55,3 +82,76 @@ func HelmUninstallHandler(ctx context.Context, app *ksctlHelm.App) error {
 	}
 	return nil
 }
+
+// HelmUpgradeHandler brings the releases of the app to the chart version and
+// values it currently describes, releases which are missing get installed
+// (same as `helm upgrade --install --wait`). Like an install it only returns
+// once the resources of the release are ready, or the timeout passed.
+func HelmUpgradeHandler(ctx context.Context, c *rest.Config, app *ksctlHelm.App, timeout time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "helm.upgrade", helmAttributes(app)...)
+	defer func() { tracing.End(span, err) }()
+
+	settings := newHelmSettings()
+
+	for _, chart := range app.Charts {
+		cfg, err := newHelmActionConfig(ctx, c, chart.Namespace)
+		if err != nil {
+			return err
+		}
+
+		history := action.NewHistory(cfg)
+		history.Max = 1
+		_, histErr := history.Run(chart.ReleaseName)
+		if histErr != nil && !errors.Is(histErr, driver.ErrReleaseNotFound) {
+			return histErr
+		}
+
+		if errors.Is(histErr, driver.ErrReleaseNotFound) {
+			install := action.NewInstall(cfg)
+			install.Namespace = chart.Namespace
+			install.ReleaseName = chart.ReleaseName
+			install.CreateNamespace = chart.CreateNamespace
+			install.Wait = true
+			install.Timeout = timeout
+
+			chartRequested, err := locateAndLoadChart(&install.ChartPathOptions, settings, app, chart)
+			if err != nil {
+				return err
+			}
+			if _, err := install.RunWithContext(ctx, chartRequested, chart.Args); err != nil {
+				return err
+			}
+			continue
+		}
+
+		upgrade := action.NewUpgrade(cfg)
+		upgrade.Namespace = chart.Namespace
+		upgrade.Wait = true
+		upgrade.Timeout = timeout
+
+		chartRequested, err := locateAndLoadChart(&upgrade.ChartPathOptions, settings, app, chart)
+		if err != nil {
+			return err
+		}
+		if _, err := upgrade.RunWithContext(ctx, chart.ReleaseName, chartRequested, chart.Args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func helmAttributes(app *ksctlHelm.App) []attribute.KeyValue {
+	var charts, versions, releases, namespaces []string
+	for _, chart := range app.Charts {
+		charts = append(charts, chart.Name)
+		versions = append(versions, chart.Version)
+		releases = append(releases, chart.ReleaseName)
+		namespaces = append(namespaces, chart.Namespace)
+	}
+	return []attribute.KeyValue{
+		tracing.AttrHelmChart.StringSlice(charts),
+		tracing.AttrHelmChartVer.StringSlice(versions),
+		tracing.AttrHelmRelease.StringSlice(releases),
+		tracing.AttrHelmNamespace.StringSlice(namespaces),
+	}
+}
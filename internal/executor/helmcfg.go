@@ -0,0 +1,127 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ksctlHelm "github.com/ksctl/ksctl/v2/pkg/helm"
+	"helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// restClientGetter lets the helm sdk talk to the cluster through the manager's
+// rest config instead of a kubeconfig file.
+type restClientGetter struct {
+	config    *rest.Config
+	namespace string
+}
+
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.config), nil
+}
+
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(g.config)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(dc), nil
+}
+
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	dc, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+	return restmapper.NewShortcutExpander(mapper, dc, nil), nil
+}
+
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{},
+		&clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: g.namespace}},
+	)
+}
+
+func newHelmActionConfig(ctx context.Context, c *rest.Config, namespace string) (*action.Configuration, error) {
+	l := log.FromContext(ctx)
+
+	cfg := new(action.Configuration)
+	if err := cfg.Init(
+		&restClientGetter{config: c, namespace: namespace},
+		namespace,
+		"secret",
+		func(format string, v ...any) {
+			l.V(1).Info(fmt.Sprintf(format, v...), "module", "helm-sdk")
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	registryClient, err := registry.NewClient(registry.ClientOptEnableCache(true))
+	if err != nil {
+		return nil, err
+	}
+	cfg.RegistryClient = registryClient
+
+	return cfg, nil
+}
+
+func newHelmSettings() *cli.EnvSettings {
+	settings := cli.New()
+	if v, ok := os.LookupEnv("HELMOCI_CHARTS_DIR"); ok {
+		settings.RepositoryCache = filepath.Join(v, "repository")
+	} else {
+		settings.RepositoryCache = filepath.Join(os.TempDir(), "helm", "repository")
+	}
+	return settings
+}
+
+// chartReference returns what the helm sdk needs to locate the chart, the
+// ksctl chart names are prefixed with the repo name (e.g. jetstack/cert-manager)
+// which only makes sense with a local repository file.
+func chartReference(app *ksctlHelm.App, chart ksctlHelm.ChartOptions) string {
+	if len(chart.ChartRef) != 0 {
+		return chart.ChartRef
+	}
+	return strings.TrimPrefix(chart.Name, app.RepoName+"/")
+}
+
+// locateAndLoadChart points the chart options of the action at the version of
+// the chart and loads it, pulling it first when needed.
+func locateAndLoadChart(
+	opts *action.ChartPathOptions,
+	settings *cli.EnvSettings,
+	app *ksctlHelm.App,
+	chart ksctlHelm.ChartOptions,
+) (*helmchart.Chart, error) {
+	opts.Version = chart.Version
+	if opts.Version == "latest" {
+		// helm takes the newest version for an empty one, "latest" is no
+		// version constraint to it
+		opts.Version = ""
+	}
+	if len(chart.ChartRef) == 0 {
+		opts.RepoURL = app.RepoUrl
+	}
+	chartPath, err := opts.LocateChart(chartReference(app, chart), settings)
+	if err != nil {
+		return nil, err
+	}
+	return loader.Load(chartPath)
+}
@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	ksctlHelm "github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestLocateAndLoadChart(t *testing.T) {
+	dir := t.TempDir()
+	chartDir, err := chartutil.Create("demo", t.TempDir())
+	require.NoError(t, err)
+	chrt, err := loader.Load(chartDir)
+	require.NoError(t, err)
+	_, err = chartutil.Save(chrt, dir)
+	require.NoError(t, err)
+	index, err := repo.IndexDirectory(dir, "")
+	require.NoError(t, err)
+	require.NoError(t, index.WriteFile(filepath.Join(dir, "index.yaml"), 0o644))
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	t.Setenv("HELMOCI_CHARTS_DIR", t.TempDir())
+	app := &ksctlHelm.App{RepoName: "example", RepoUrl: srv.URL}
+
+	for _, version := range []string{"latest", "", chrt.Metadata.Version} {
+		opts := &action.ChartPathOptions{}
+		loaded, err := locateAndLoadChart(opts, newHelmSettings(), app, ksctlHelm.ChartOptions{
+			Name:    "example/demo",
+			Version: version,
+		})
+		if assert.NoError(t, err, version) {
+			assert.Equal(t, chrt.Metadata.Version, loaded.Metadata.Version)
+		}
+	}
+}
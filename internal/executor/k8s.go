@@ -8,13 +8,18 @@ import (
 	"github.com/ksctl/ksctl/v2/pkg/k8s"
 	"github.com/ksctl/ksctl/v2/pkg/logger"
 	"k8s.io/client-go/rest"
+
+	"github.com/ksctl/ka/internal/tracing"
 )
 
 func K8sDeployHandler(
 	ctx context.Context,
 	c *rest.Config,
 	app *k8s.App,
-) error {
+) (err error) {
+	ctx, span := tracing.Start(ctx, "kubectl.apply", tracing.AttrManifestURLs.StringSlice(app.Urls))
+	defer func() { tracing.End(span, err) }()
+
 	obj, err := k8s.NewK8sClient(context.WithValue(ctx, consts.KsctlModuleNameKey, "ksctl.com/k8s-client"), logger.NewStructuredLogger(-1, os.Stdout), c)
 	if err != nil {
 		return err
@@ -27,7 +32,10 @@ func K8sUninstallHandler(
 	ctx context.Context,
 	c *rest.Config,
 	app *k8s.App,
-) error {
+) (err error) {
+	ctx, span := tracing.Start(ctx, "kubectl.delete", tracing.AttrManifestURLs.StringSlice(app.Urls))
+	defer func() { tracing.End(span, err) }()
+
 	obj, err := k8s.NewK8sClient(context.WithValue(ctx, consts.KsctlModuleNameKey, "ksctl.com/k8s-client"), logger.NewStructuredLogger(-1, os.Stdout), c)
 	if err != nil {
 		return err
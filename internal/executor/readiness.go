@@ -0,0 +1,165 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ksctlHelm "github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/ksctl/ksctl/v2/pkg/k8s"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadinessCheck returns what keeps a component from being ready, nothing
+// once it is.
+type ReadinessCheck func(ctx context.Context) ([]string, error)
+
+var (
+	deploymentGK = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	daemonSetGK  = schema.GroupKind{Group: "apps", Kind: "DaemonSet"}
+	crdGK        = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+)
+
+// HelmReadinessCheck expects every release of the app to be deployed along with
+// the Deployments, DaemonSets and CRDs it holds being available.
+func HelmReadinessCheck(ctx context.Context, c *rest.Config, app *ksctlHelm.App) (ReadinessCheck, error) {
+	cl, err := client.New(c, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) ([]string, error) {
+		var notReady []string
+		for _, chart := range app.Charts {
+			cfg, err := newHelmActionConfig(ctx, c, chart.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			rel, err := action.NewGet(cfg).Run(chart.ReleaseName)
+			if err != nil {
+				return nil, err
+			}
+			if rel.Info == nil || rel.Info.Status != release.StatusDeployed {
+				notReady = append(notReady, fmt.Sprintf("helm release %s/%s is not deployed", chart.Namespace, chart.ReleaseName))
+				continue
+			}
+
+			objs, err := decodeManifestObjects(strings.NewReader(rel.Manifest))
+			if err != nil {
+				return nil, err
+			}
+			n, err := objectsNotReady(ctx, cl, objs, chart.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			notReady = append(notReady, n...)
+		}
+		return notReady, nil
+	}, nil
+}
+
+// K8sReadinessCheck expects the Deployments, DaemonSets and CRDs of the app
+// manifests to be available.
+func K8sReadinessCheck(ctx context.Context, c *rest.Config, app *k8s.App) (ReadinessCheck, error) {
+	cl, err := client.New(c, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, url := range app.Urls {
+		o, err := fetchManifestObjects(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, o...)
+	}
+
+	return func(ctx context.Context) ([]string, error) {
+		return objectsNotReady(ctx, cl, objs, app.Namespace)
+	}, nil
+}
+
+func objectsNotReady(ctx context.Context, cl client.Client, objs []*unstructured.Unstructured, namespace string) ([]string, error) {
+	var notReady []string
+	for _, obj := range objs {
+		gk := obj.GroupVersionKind().GroupKind()
+		if gk != deploymentGK && gk != daemonSetGK && gk != crdGK {
+			continue
+		}
+
+		key := client.ObjectKey{Name: obj.GetName()}
+		if gk != crdGK {
+			key.Namespace = obj.GetNamespace()
+			if len(key.Namespace) == 0 {
+				key.Namespace = namespace
+			}
+			if len(key.Namespace) == 0 {
+				key.Namespace = "default"
+			}
+		}
+
+		found := &unstructured.Unstructured{}
+		found.SetGroupVersionKind(obj.GroupVersionKind())
+		if err := cl.Get(ctx, key, found); apierrors.IsNotFound(err) {
+			notReady = append(notReady, fmt.Sprintf("%s %s not found", gk.Kind, key))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if reason := objectNotReady(found); len(reason) != 0 {
+			notReady = append(notReady, fmt.Sprintf("%s %s %s", gk.Kind, key, reason))
+		}
+	}
+	return notReady, nil
+}
+
+// objectNotReady tells why the object is not ready, nothing once it is.
+func objectNotReady(obj *unstructured.Unstructured) string {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+
+	switch obj.GroupVersionKind().GroupKind() {
+	case deploymentGK:
+		if observed < obj.GetGeneration() {
+			return "is being rolled out"
+		}
+		replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+		available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+		if updated < replicas || available < replicas {
+			return fmt.Sprintf("has %d/%d replicas available", available, replicas)
+		}
+
+	case daemonSetGK:
+		if observed < obj.GetGeneration() {
+			return "is being rolled out"
+		}
+		desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedNumberScheduled")
+		available, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberAvailable")
+		if updated < desired || available < desired {
+			return fmt.Sprintf("has %d/%d pods available", available, desired)
+		}
+
+	case crdGK:
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			cond, ok := c.(map[string]any)
+			if ok && cond["type"] == "Established" && cond["status"] == "True" {
+				return ""
+			}
+		}
+		return "is not established"
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestObjectNotReady(t *testing.T) {
+	testCases := []struct {
+		name   string
+		obj    map[string]any
+		reason string
+	}{
+		{
+			name: "deployment available",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": map[string]any{"generation": int64(2)},
+				"spec":     map[string]any{"replicas": int64(2)},
+				"status": map[string]any{
+					"observedGeneration": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2),
+				},
+			},
+		},
+		{
+			name: "deployment defaults to one replica",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": map[string]any{"generation": int64(1)},
+				"status":   map[string]any{"observedGeneration": int64(1)},
+			},
+			reason: "has 0/1 replicas available",
+		},
+		{
+			name: "deployment not observed yet",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": map[string]any{"generation": int64(3)},
+				"status":   map[string]any{"observedGeneration": int64(2)},
+			},
+			reason: "is being rolled out",
+		},
+		{
+			name: "daemonset partially available",
+			obj: map[string]any{
+				"apiVersion": "apps/v1", "kind": "DaemonSet",
+				"status": map[string]any{
+					"desiredNumberScheduled": int64(3), "updatedNumberScheduled": int64(3), "numberAvailable": int64(1),
+				},
+			},
+			reason: "has 1/3 pods available",
+		},
+		{
+			name: "crd established",
+			obj: map[string]any{
+				"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition",
+				"status": map[string]any{"conditions": []any{
+					map[string]any{"type": "NamesAccepted", "status": "True"},
+					map[string]any{"type": "Established", "status": "True"},
+				}},
+			},
+		},
+		{
+			name: "crd not established",
+			obj: map[string]any{
+				"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition",
+			},
+			reason: "is not established",
+		},
+		{
+			name: "other kinds are always ready",
+			obj:  map[string]any{"apiVersion": "v1", "kind": "Service"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.reason, objectNotReady(&unstructured.Unstructured{Object: tc.obj}))
+		})
+	}
+}
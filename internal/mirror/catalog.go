@@ -0,0 +1,38 @@
+package mirror
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+var ErrNotInCatalog = errors.New("not in the version catalog")
+
+// Catalog holds the releases of the upstream projects, newest first, keyed by
+// "org/repo". It stands in for the GitHub release poller where GitHub can't
+// be reached.
+type Catalog map[string][]string
+
+// LoadCatalog reads a catalog from a YAML or JSON file, e.g. a mounted ConfigMap.
+func LoadCatalog(path string) (Catalog, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := yaml.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("version catalog %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Get implements the poller of ksctl.
+func (c Catalog) Get(org, repo string) ([]string, error) {
+	releases := c[org+"/"+repo]
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotInCatalog, org, repo)
+	}
+	return releases, nil
+}
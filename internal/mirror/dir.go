@@ -0,0 +1,53 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DirServer serves a mirror directory, e.g. a mounted volume holding an
+// unpacked bundle, on the loopback interface as helm and kubectl only fetch
+// over HTTP.
+type DirServer struct {
+	Dir      string
+	listener net.Listener
+}
+
+func NewDirServer(dir string) (*DirServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return &DirServer{Dir: dir, listener: l}, nil
+}
+
+// URL is the HTTP base the directory is served at.
+func (s *DirServer) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Start serves the directory until the context is done, it implements
+// manager.Runnable.
+func (s *DirServer) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Handler:           http.FileServer(http.Dir(s.Dir)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	if err := srv.Serve(s.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection lets every replica serve the directory, the webhooks
+// render stacks too.
+func (s *DirServer) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,144 @@
+package mirror
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/ksctl/ksctl/v2/pkg/k8s"
+)
+
+var ErrInvalidMirror = errors.New("invalid mirror")
+
+const (
+	// ChartsDir holds the charts of an HTTP mirror along with their index.yaml,
+	// it is served as a single helm repository.
+	ChartsDir string = "charts"
+	// ManifestsDir holds the manifests of an HTTP mirror, each one under the
+	// host and path of its upstream URL.
+	ManifestsDir string = "manifests"
+	// RepoName is the name the helm repository of an HTTP mirror is added as.
+	RepoName string = "ka-mirror"
+)
+
+const ociScheme string = "oci://"
+
+// Mirror points the components at a local copy of their charts and manifests,
+// so stacks install without reaching the internet.
+type Mirror struct {
+	// Charts is the OCI registry (oci://host/path) the charts are pulled from,
+	// or the HTTP base serving the ChartsDir helm repository.
+	Charts string
+	// Manifests is the HTTP base serving the ManifestsDir.
+	Manifests string
+}
+
+// New checks the mirror, the manifests come from the charts mirror unless
+// that is an OCI registry.
+func New(charts, manifests string) (*Mirror, error) {
+	charts = strings.TrimSuffix(charts, "/")
+	manifests = strings.TrimSuffix(manifests, "/")
+
+	if !strings.HasPrefix(charts, ociScheme) && !isHTTP(charts) {
+		return nil, fmt.Errorf("%w: charts mirror %q is neither oci:// nor http(s)://", ErrInvalidMirror, charts)
+	}
+	if len(manifests) == 0 {
+		if !isHTTP(charts) {
+			return nil, fmt.Errorf("%w: an OCI charts mirror needs an HTTP manifests mirror", ErrInvalidMirror)
+		}
+		manifests = charts
+	}
+	if !isHTTP(manifests) {
+		return nil, fmt.Errorf("%w: manifests mirror %q is not http(s)://", ErrInvalidMirror, manifests)
+	}
+	return &Mirror{Charts: charts, Manifests: manifests}, nil
+}
+
+func isHTTP(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) != 0
+}
+
+// ChartName is the name of the chart without its repository, e.g.
+// cert-manager for jetstack/cert-manager.
+func ChartName(chart helm.ChartOptions) string {
+	if len(chart.ChartRef) != 0 {
+		return path.Base(chart.ChartRef)
+	}
+	return path.Base(chart.Name)
+}
+
+// ChartFile is the archive the chart is stored as, the same name helm pulls
+// it as.
+func ChartFile(chart helm.ChartOptions) string {
+	return fmt.Sprintf("%s-%s.tgz", ChartName(chart), chart.Version)
+}
+
+// ManifestPath is where the manifest of the upstream URL is kept in the
+// mirror, relative to its base.
+func ManifestPath(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if len(u.Host) == 0 {
+		return "", fmt.Errorf("%w: manifest url %q has no host", ErrInvalidMirror, rawURL)
+	}
+	return path.Join(ManifestsDir, u.Host, path.Clean("/"+u.Path)), nil
+}
+
+// Rewrite points every component of the stack at the mirror.
+func (m *Mirror) Rewrite(stk stack.ApplicationStack) (stack.ApplicationStack, error) {
+	components := make(map[stack.ComponentID]stack.Component, len(stk.Components))
+	for id, c := range stk.Components {
+		var err error
+		if c.Helm != nil {
+			c.Helm = m.rewriteHelm(c.Helm)
+		}
+		if c.Kubectl != nil {
+			if c.Kubectl, err = m.rewriteKubectl(c.Kubectl); err != nil {
+				return stack.ApplicationStack{}, fmt.Errorf("component %s: %w", id, err)
+			}
+		}
+		components[id] = c
+	}
+	stk.Components = components
+	return stk, nil
+}
+
+func (m *Mirror) rewriteHelm(app *helm.App) *helm.App {
+	rewritten := &helm.App{Charts: make([]helm.ChartOptions, 0, len(app.Charts))}
+	if !strings.HasPrefix(m.Charts, ociScheme) {
+		rewritten.RepoName = RepoName
+		rewritten.RepoUrl = m.Charts + "/" + ChartsDir
+	}
+	for _, chart := range app.Charts {
+		name := ChartName(chart)
+		if strings.HasPrefix(m.Charts, ociScheme) {
+			chart.ChartRef = m.Charts + "/" + name
+			chart.Name = "./" + ChartFile(chart)
+		} else {
+			chart.ChartRef = ""
+			chart.Name = RepoName + "/" + name
+		}
+		rewritten.Charts = append(rewritten.Charts, chart)
+	}
+	return rewritten
+}
+
+func (m *Mirror) rewriteKubectl(app *k8s.App) (*k8s.App, error) {
+	rewritten := *app
+	rewritten.Urls = make([]string, 0, len(app.Urls))
+	for _, u := range app.Urls {
+		p, err := ManifestPath(u)
+		if err != nil {
+			return nil, err
+		}
+		rewritten.Urls = append(rewritten.Urls, m.Manifests+"/"+p)
+	}
+	return &rewritten, nil
+}
@@ -0,0 +1,157 @@
+package mirror
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/ksctl/ksctl/v2/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStack() stack.ApplicationStack {
+	return stack.ApplicationStack{
+		StkDepsIdx: []stack.ComponentID{"cert-manager", "spinkube-operator-crd", "spinkube-operator"},
+		Components: map[stack.ComponentID]stack.Component{
+			"cert-manager": {
+				HandlerType: stack.ComponentTypeHelm,
+				Helm: &helm.App{
+					RepoUrl:  "https://charts.jetstack.io",
+					RepoName: "jetstack",
+					Charts: []helm.ChartOptions{{
+						Name:        "jetstack/cert-manager",
+						Version:     "v1.15.3",
+						ReleaseName: "cert-manager",
+						Namespace:   "cert-manager",
+					}},
+				},
+			},
+			"spinkube-operator-crd": {
+				HandlerType: stack.ComponentTypeKubectl,
+				Kubectl: &k8s.App{
+					Urls:    []string{"https://github.com/spinkube/spin-operator/releases/download/v0.4.0/spin-operator.crds.yaml"},
+					Version: "v0.4.0",
+				},
+			},
+			"spinkube-operator": {
+				HandlerType: stack.ComponentTypeHelm,
+				Helm: &helm.App{
+					Charts: []helm.ChartOptions{{
+						Name:        "./spin-operator-0.4.0.tgz",
+						Version:     "0.4.0",
+						ReleaseName: "spin-operator",
+						Namespace:   "spin-operator",
+						ChartRef:    "oci://ghcr.io/spinkube/charts/spin-operator",
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	m, err := New("http://mirror.local/ka/", "")
+	assert.NoError(t, err)
+	assert.Equal(t, &Mirror{Charts: "http://mirror.local/ka", Manifests: "http://mirror.local/ka"}, m)
+
+	_, err = New("oci://registry.local/ka", "")
+	assert.ErrorIs(t, err, ErrInvalidMirror)
+
+	_, err = New("registry.local/ka", "")
+	assert.ErrorIs(t, err, ErrInvalidMirror)
+
+	_, err = New("oci://registry.local/ka", "oci://registry.local/manifests")
+	assert.ErrorIs(t, err, ErrInvalidMirror)
+}
+
+func TestRewriteHTTP(t *testing.T) {
+	m, err := New("http://mirror.local/ka", "")
+	assert.NoError(t, err)
+
+	upstream := testStack()
+	stk, err := m.Rewrite(upstream)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &helm.App{
+		RepoUrl:  "http://mirror.local/ka/charts",
+		RepoName: RepoName,
+		Charts: []helm.ChartOptions{{
+			Name:        "ka-mirror/cert-manager",
+			Version:     "v1.15.3",
+			ReleaseName: "cert-manager",
+			Namespace:   "cert-manager",
+		}},
+	}, stk.Components["cert-manager"].Helm)
+	assert.Equal(t, "ka-mirror/spin-operator", stk.Components["spinkube-operator"].Helm.Charts[0].Name)
+	assert.Empty(t, stk.Components["spinkube-operator"].Helm.Charts[0].ChartRef)
+	assert.Equal(t, []string{
+		"http://mirror.local/ka/manifests/github.com/spinkube/spin-operator/releases/download/v0.4.0/spin-operator.crds.yaml",
+	}, stk.Components["spinkube-operator-crd"].Kubectl.Urls)
+
+	assert.Equal(t, "https://charts.jetstack.io", upstream.Components["cert-manager"].Helm.RepoUrl, "the upstream stack is left untouched")
+}
+
+func TestRewriteOCI(t *testing.T) {
+	m, err := New("oci://registry.local/ka", "http://mirror.local/ka")
+	assert.NoError(t, err)
+
+	stk, err := m.Rewrite(testStack())
+	assert.NoError(t, err)
+
+	certManager := stk.Components["cert-manager"].Helm
+	assert.Empty(t, certManager.RepoUrl)
+	assert.Equal(t, "oci://registry.local/ka/cert-manager", certManager.Charts[0].ChartRef)
+	assert.Equal(t, "./cert-manager-v1.15.3.tgz", certManager.Charts[0].Name)
+	assert.Equal(t, "oci://registry.local/ka/spin-operator", stk.Components["spinkube-operator"].Helm.Charts[0].ChartRef)
+	assert.Equal(t, "./spin-operator-0.4.0.tgz", stk.Components["spinkube-operator"].Helm.Charts[0].Name)
+}
+
+func TestManifestPath(t *testing.T) {
+	p, err := ManifestPath("https://raw.githubusercontent.com/argoproj/argo-cd/v2.14.2/manifests/../manifests/install.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "manifests/raw.githubusercontent.com/argoproj/argo-cd/v2.14.2/manifests/install.yaml", p)
+
+	_, err = ManifestPath("install.yaml")
+	assert.ErrorIs(t, err, ErrInvalidMirror)
+}
+
+func TestCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("istio/istio:\n- 1.22.4\n- 1.21.0\n"), 0o644))
+
+	c, err := LoadCatalog(path)
+	assert.NoError(t, err)
+	releases, err := c.Get("istio", "istio")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.22.4", "1.21.0"}, releases)
+
+	_, err = c.Get("argoproj", "argo-rollouts")
+	assert.ErrorIs(t, err, ErrNotInCatalog)
+}
+
+func TestDirServer(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ChartsDir), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ChartsDir, "index.yaml"), []byte("apiVersion: v1\n"), 0o644))
+
+	s, err := NewDirServer(dir)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Start(ctx) }()
+
+	resp, err := http.Get(s.URL() + "/" + ChartsDir + "/index.yaml")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "apiVersion: v1\n", string(body))
+
+	cancel()
+	assert.NoError(t, <-done)
+}
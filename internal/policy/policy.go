@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/stacks"
+)
+
+var (
+	ErrNotAllowed      = errors.New("stack not allowed")
+	ErrPolicyViolation = errors.New("policy violation")
+)
+
+const namespaceKey string = "namespace"
+
+// Applies tells whether the policy applies to the namespace.
+func Applies(p *appv1.StackPolicy, ns *corev1.Namespace) (bool, error) {
+	if slices.Contains(p.Spec.Namespaces, ns.Name) {
+		return true, nil
+	}
+	if p.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("StackPolicy %s: %w", p.Name, err)
+	}
+	return selector.Matches(labels.Set(ns.Labels)), nil
+}
+
+// Admission is how a StackInstance gets installed once admitted.
+type Admission struct {
+	// Spec of the Stack installing the instance.
+	Spec appv1.StackSpec
+	// RequireApproval is set when the plans of the Stack are approved on the
+	// Stack only, see appv1.AllowedStack.
+	RequireApproval bool
+}
+
+// Admit checks the spec of a StackInstance of the namespace against the
+// policies and returns the spec of the Stack it gets installed as: the enforced
+// overrides applied and every component accepting a namespace installed into
+// the namespace of the instance.
+func Admit(policies []appv1.StackPolicy, ns *corev1.Namespace, spec appv1.StackSpec) (Admission, error) {
+	var rules []appv1.AllowedStack
+	for i := range policies {
+		ok, err := Applies(&policies[i], ns)
+		if err != nil {
+			return Admission{}, err
+		}
+		if !ok {
+			continue
+		}
+		for _, rule := range policies[i].Spec.Stacks {
+			if rule.StackName == spec.StackName {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	if len(rules) == 0 {
+		return Admission{}, fmt.Errorf("%w: no StackPolicy allows %s in namespace %s", ErrNotAllowed, spec.StackName, ns.Name)
+	}
+
+	var errs []error
+	for _, rule := range rules {
+		admitted, err := admit(rule, ns.Name, spec)
+		if err == nil {
+			return Admission{Spec: admitted, RequireApproval: rule.RequireApproval}, nil
+		}
+		errs = append(errs, err)
+	}
+	return Admission{}, errors.Join(errs...)
+}
+
+func admit(rule appv1.AllowedStack, namespace string, spec appv1.StackSpec) (appv1.StackSpec, error) {
+	schemas, ok := stacks.GetComponentOverridings(spec.StackName)
+	if !ok {
+		return appv1.StackSpec{}, fmt.Errorf("%w: unknown stack %s", ErrNotAllowed, spec.StackName)
+	}
+
+	overrides, err := decodeOverrides(spec.Overrides)
+	if err != nil {
+		return appv1.StackSpec{}, err
+	}
+	enforced, err := decodeOverrides(rule.EnforcedOverrides)
+	if err != nil {
+		return appv1.StackSpec{}, fmt.Errorf("enforced overrides of %s: %w", rule.StackName, err)
+	}
+
+	var violations []string
+	for _, id := range slices.Sorted(maps.Keys(schemas)) {
+		if len(rule.Components) != 0 &&
+			!slices.Contains(rule.Components, string(id)) &&
+			!slices.Contains(spec.DisableComponents, string(id)) {
+			violations = append(violations, fmt.Sprintf("component %s is not allowed and has to be disabled", id))
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(overrides)) {
+		allowed := rule.Overrides[id]
+		for _, key := range slices.Sorted(maps.Keys(overrides[id])) {
+			if key == namespaceKey {
+				if overrides[id][key] != namespace {
+					violations = append(violations, fmt.Sprintf("component %s can only be installed into namespace %s", id, namespace))
+				}
+				continue
+			}
+			if !slices.Contains(allowed, appv1.AnyOverride) && !slices.Contains(allowed, key) {
+				violations = append(violations, fmt.Sprintf("override %s of component %s is not allowed", key, id))
+			}
+		}
+	}
+	if len(violations) != 0 {
+		return appv1.StackSpec{}, fmt.Errorf("%w: %s", ErrPolicyViolation, strings.Join(violations, ", "))
+	}
+
+	for id, o := range enforced {
+		if overrides[id] == nil {
+			overrides[id] = map[string]any{}
+		}
+		maps.Copy(overrides[id], o)
+	}
+	for id, schema := range schemas {
+		if _, ok := schema[namespaceKey]; !ok {
+			continue
+		}
+		if overrides[string(id)] == nil {
+			overrides[string(id)] = map[string]any{}
+		}
+		overrides[string(id)][namespaceKey] = namespace
+	}
+
+	raw, err := json.Marshal(overrides)
+	if err != nil {
+		return appv1.StackSpec{}, err
+	}
+	admitted := *spec.DeepCopy()
+	admitted.Overrides = &apiextensionsv1.JSON{Raw: raw}
+	admitted.RequireApproval = admitted.RequireApproval || rule.RequireApproval
+	return admitted, nil
+}
+
+func decodeOverrides(raw *apiextensionsv1.JSON) (map[string]map[string]any, error) {
+	overrides := map[string]map[string]any{}
+	if raw == nil {
+		return overrides, nil
+	}
+	if err := json.Unmarshal(raw.Raw, &overrides); err != nil {
+		return nil, err
+	}
+	for id, o := range overrides {
+		if o == nil {
+			overrides[id] = map[string]any{}
+		}
+	}
+	return overrides, nil
+}
@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func gitopsPolicy() appv1.StackPolicy {
+	return appv1.StackPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "self-service"},
+		Spec: appv1.StackPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"self-service": "true"}},
+			Stacks: []appv1.AllowedStack{{
+				StackName:  "gitops-standard",
+				Components: []string{"argocd"},
+				Overrides:  map[string][]string{"argocd": {"version"}},
+				EnforcedOverrides: &apiextensionsv1.JSON{
+					Raw: []byte(`{"argocd":{"namespaceInstall":true}}`),
+				},
+			}},
+		},
+	}
+}
+
+func overridesOf(t *testing.T, spec appv1.StackSpec) map[string]map[string]any {
+	overrides := map[string]map[string]any{}
+	assert.NoError(t, json.Unmarshal(spec.Overrides.Raw, &overrides))
+	return overrides
+}
+
+func TestApplies(t *testing.T) {
+	p := gitopsPolicy()
+	p.Spec.Namespaces = []string{"team-b"}
+
+	ok, err := Applies(&p, namespace("team-a", map[string]string{"self-service": "true"}))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Applies(&p, namespace("team-b", nil))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Applies(&p, namespace("team-c", nil))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAdmit(t *testing.T) {
+	ns := namespace("team-a", map[string]string{"self-service": "true"})
+
+	admission, err := Admit([]appv1.StackPolicy{gitopsPolicy()}, ns, appv1.StackSpec{
+		StackName:         "gitops-standard",
+		DisableComponents: []string{"argorollouts"},
+		Overrides:         &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"version":"v2.14.2"}}`)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"argorollouts"}, admission.Spec.DisableComponents)
+	assert.Equal(t, map[string]map[string]any{
+		"argocd":       {"version": "v2.14.2", "namespaceInstall": true, "namespace": "team-a"},
+		"argorollouts": {"namespace": "team-a"},
+	}, overridesOf(t, admission.Spec))
+	assert.False(t, admission.RequireApproval)
+	assert.False(t, admission.Spec.RequireApproval)
+}
+
+func TestAdmitRequireApproval(t *testing.T) {
+	ns := namespace("team-a", map[string]string{"self-service": "true"})
+	p := gitopsPolicy()
+	p.Spec.Stacks[0].RequireApproval = true
+
+	admission, err := Admit([]appv1.StackPolicy{p}, ns, appv1.StackSpec{
+		StackName:         "gitops-standard",
+		DisableComponents: []string{"argorollouts"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, admission.RequireApproval)
+	assert.True(t, admission.Spec.RequireApproval)
+}
+
+func TestAdmitRejects(t *testing.T) {
+	ns := namespace("team-a", map[string]string{"self-service": "true"})
+	policies := []appv1.StackPolicy{gitopsPolicy()}
+
+	_, err := Admit(policies, namespace("team-c", nil), appv1.StackSpec{StackName: "gitops-standard"})
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	_, err = Admit(policies, ns, appv1.StackSpec{StackName: "monitoring-lite"})
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	_, err = Admit(policies, ns, appv1.StackSpec{StackName: "gitops-standard"})
+	assert.ErrorIs(t, err, ErrPolicyViolation)
+	assert.ErrorContains(t, err, "component argorollouts is not allowed")
+
+	_, err = Admit(policies, ns, appv1.StackSpec{
+		StackName:         "gitops-standard",
+		DisableComponents: []string{"argorollouts"},
+		Overrides:         &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"noUI":true,"namespace":"argocd"}}`)},
+	})
+	assert.ErrorIs(t, err, ErrPolicyViolation)
+	assert.ErrorContains(t, err, "component argocd can only be installed into namespace team-a")
+	assert.ErrorContains(t, err, "override noUI of component argocd is not allowed")
+}
+
+func TestAdmitAnyRule(t *testing.T) {
+	ns := namespace("team-a", map[string]string{"self-service": "true"})
+	open := appv1.StackPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+		Spec: appv1.StackPolicySpec{
+			Namespaces: []string{"team-a"},
+			Stacks: []appv1.AllowedStack{{
+				StackName: "gitops-standard",
+				Overrides: map[string][]string{"argocd": {appv1.AnyOverride}},
+			}},
+		},
+	}
+
+	_, err := Admit([]appv1.StackPolicy{gitopsPolicy(), open}, ns, appv1.StackSpec{
+		StackName: "gitops-standard",
+		Overrides: &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"noUI":true}}`)},
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,88 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{expr: "* * * * *"},
+		{expr: "0 2 * * sat,sun"},
+		{expr: "*/15 1-5/2 1,15 jan-mar 7"},
+		{expr: "@daily"},
+		{expr: "0 2 * *", wantErr: true},
+		{expr: "60 * * * *", wantErr: true},
+		{expr: "0 5-1 * * *", wantErr: true},
+		{expr: "*/0 * * * *", wantErr: true},
+		{expr: "0 0 * foo *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSchedule)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	from := time.Date(2026, time.October, 16, 10, 7, 30, 0, time.UTC) // friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "* * * * *", want: time.Date(2026, time.October, 16, 10, 8, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", want: time.Date(2026, time.October, 16, 10, 15, 0, 0, time.UTC)},
+		{expr: "0 2 * * sat,sun", want: time.Date(2026, time.October, 17, 2, 0, 0, 0, time.UTC)},
+		{expr: "30 1 * * 7", want: time.Date(2026, time.October, 18, 1, 30, 0, 0, time.UTC)},
+		{expr: "0 0 1 jan *", want: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// restricting both days matches either of them
+		{expr: "0 0 20 * mon", want: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 30 2 *", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(from))
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	w, err := NewWindow("0 2 * * sat", 3*time.Hour, "Asia/Kolkata")
+	require.NoError(t, err)
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	opens := time.Date(2026, time.October, 17, 2, 0, 0, 0, kolkata)
+
+	open, _ := w.Open(opens.Add(-time.Minute))
+	assert.False(t, open)
+	assert.True(t, w.Next(opens.Add(-time.Minute)).Equal(opens))
+
+	open, closes := w.Open(opens.Add(90 * time.Minute).UTC())
+	assert.True(t, open)
+	assert.True(t, closes.Equal(opens.Add(3*time.Hour)))
+
+	open, _ = w.Open(opens.Add(3 * time.Hour))
+	assert.False(t, open)
+	assert.True(t, w.Next(opens).Equal(opens.AddDate(0, 0, 7)))
+
+	_, err = NewWindow("0 2 * * sat", 0, "")
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+	_, err = NewWindow("0 2 * * sat", time.Hour, "Mars/Olympus")
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+}
@@ -0,0 +1,96 @@
+package stacks
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+)
+
+var (
+	ErrDependencyCycle   = errors.New("dependency cycle")
+	ErrUnknownDependency = errors.New("unknown dependency")
+)
+
+// GetDependencies returns what every component of the stack needs installed
+// before it. Stacks without an explicit graph depend on the order of their
+// dependency index, each component on the one before it.
+func GetDependencies(stkID string, manifest stack.ApplicationStack) map[stack.ComponentID][]stack.ComponentID {
+	if deps, ok := stackDependencies[stack.ID(stkID)]; ok {
+		return deps
+	}
+	deps := make(map[stack.ComponentID][]stack.ComponentID, len(manifest.StkDepsIdx))
+	for i, componentId := range manifest.StkDepsIdx {
+		if i > 0 {
+			deps[componentId] = []stack.ComponentID{manifest.StkDepsIdx[i-1]}
+		}
+	}
+	return deps
+}
+
+// GetInstallLevels groups the components of the dependency index into levels,
+// the components of a level only depend on the ones of the levels before it
+// so they can be installed at the same time. Within a level the components
+// keep the order of the dependency index.
+func GetInstallLevels(stkID string, manifest stack.ApplicationStack) ([][]stack.ComponentID, error) {
+	return InstallLevels(manifest.StkDepsIdx, GetDependencies(stkID, manifest))
+}
+
+func InstallLevels(
+	order []stack.ComponentID,
+	deps map[stack.ComponentID][]stack.ComponentID,
+) ([][]stack.ComponentID, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	marks := make(map[stack.ComponentID]int, len(order))
+	levelOf := make(map[stack.ComponentID]int, len(order))
+
+	var visit func(componentId stack.ComponentID, path []stack.ComponentID) error
+	visit = func(componentId stack.ComponentID, path []stack.ComponentID) error {
+		switch marks[componentId] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, componentId):]), componentId)
+			names := make([]string, 0, len(cycle))
+			for _, c := range cycle {
+				names = append(names, string(c))
+			}
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(names, " -> "))
+		}
+
+		marks[componentId] = visiting
+		level := 0
+		for _, dep := range deps[componentId] {
+			if !slices.Contains(order, dep) {
+				return fmt.Errorf("%w: %s depends on %s which is not part of the stack", ErrUnknownDependency, componentId, dep)
+			}
+			if err := visit(dep, append(path, componentId)); err != nil {
+				return err
+			}
+			level = max(level, levelOf[dep]+1)
+		}
+		marks[componentId] = visited
+		levelOf[componentId] = level
+		return nil
+	}
+
+	var levels [][]stack.ComponentID
+	for _, componentId := range order {
+		if err := visit(componentId, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, componentId := range order {
+		level := levelOf[componentId]
+		for len(levels) <= level {
+			levels = append(levels, nil)
+		}
+		levels[level] = append(levels[level], componentId)
+	}
+	return levels, nil
+}
@@ -0,0 +1,56 @@
+package stacks
+
+import (
+	"testing"
+
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallLevels(t *testing.T) {
+	order := []stack.ComponentID{"cert-manager", "kwasm", "crd", "runtime-class", "shim-executor", "operator"}
+	deps := map[stack.ComponentID][]stack.ComponentID{
+		"shim-executor": {"crd"},
+		"operator":      {"cert-manager", "kwasm", "crd", "runtime-class", "shim-executor"},
+	}
+
+	levels, err := InstallLevels(order, deps)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]stack.ComponentID{
+		{"cert-manager", "kwasm", "crd", "runtime-class"},
+		{"shim-executor"},
+		{"operator"},
+	}, levels)
+}
+
+func TestInstallLevelsCycle(t *testing.T) {
+	_, err := InstallLevels(
+		[]stack.ComponentID{"a", "b", "c"},
+		map[stack.ComponentID][]stack.ComponentID{"a": {"c"}, "b": {"a"}, "c": {"b"}},
+	)
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+	assert.ErrorContains(t, err, "a -> c -> b -> a")
+}
+
+func TestInstallLevelsUnknownDependency(t *testing.T) {
+	_, err := InstallLevels(
+		[]stack.ComponentID{"a"},
+		map[stack.ComponentID][]stack.ComponentID{"a": {"b"}},
+	)
+	assert.ErrorIs(t, err, ErrUnknownDependency)
+}
+
+func TestGetInstallLevels(t *testing.T) {
+	manifest := stack.ApplicationStack{StkDepsIdx: []stack.ComponentID{"a", "b", "c"}}
+
+	levels, err := GetInstallLevels("unknown-stack", manifest)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]stack.ComponentID{{"a"}, {"b"}, {"c"}}, levels, "stacks without a graph install in order")
+
+	for stkID, deps := range stackDependencies {
+		for componentId := range deps {
+			_, ok := stackOverridings[stkID][componentId]
+			assert.True(t, ok, "%s: dependency graph lists unknown component %s", stkID, componentId)
+		}
+	}
+}
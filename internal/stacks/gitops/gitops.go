@@ -1,6 +1,7 @@
 package gitops
 
 import (
+	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ka/internal/apps/argocd"
 	"github.com/ksctl/ka/internal/apps/argorollouts"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
@@ -10,6 +11,18 @@ const (
 	SKU stack.ID = "gitops-standard"
 )
 
+var Overridings = map[stack.ComponentID]apps.OverridesSchema{
+	argocd.SKU:       argocd.OverridingsSchema,
+	argorollouts.SKU: argorollouts.OverridingsSchema,
+}
+
+// Dependencies lets argocd and argo-rollouts install side by side, neither
+// needs the other.
+var Dependencies = map[stack.ComponentID][]stack.ComponentID{
+	argocd.SKU:       nil,
+	argorollouts.SKU: nil,
+}
+
 func GitOps(params stack.ApplicationParams) (stack.ApplicationStack, error) {
 	v, err := argorollouts.ArgoRolloutsStandardComponent(
 		params.ComponentParams[argorollouts.SKU],
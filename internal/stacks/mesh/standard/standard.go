@@ -1,6 +1,7 @@
 package standard
 
 import (
+	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ka/internal/apps/istio"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 )
@@ -9,6 +10,14 @@ const (
 	SKU stack.ID = "mesh-standard"
 )
 
+var Overridings = map[stack.ComponentID]apps.OverridesSchema{
+	istio.SKU: istio.OverridingsSchema,
+}
+
+var Dependencies = map[stack.ComponentID][]stack.ComponentID{
+	istio.SKU: nil,
+}
+
 func MeshStandard(params stack.ApplicationParams) (stack.ApplicationStack, error) {
 
 	v, err := istio.IstioStandardComponent(
@@ -1,6 +1,7 @@
 package lite
 
 import (
+	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ka/internal/apps/kubeprometheus"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 )
@@ -9,6 +10,14 @@ const (
 	SKU stack.ID = "monitoring-lite"
 )
 
+var Overridings = map[stack.ComponentID]apps.OverridesSchema{
+	kubeprometheus.SKU: kubeprometheus.OverridingsSchema,
+}
+
+var Dependencies = map[stack.ComponentID][]stack.ComponentID{
+	kubeprometheus.SKU: nil,
+}
+
 func MonitoringLite(params stack.ApplicationParams) (stack.ApplicationStack, error) {
 	stk := stack.ApplicationStack{
 		Components: map[stack.ComponentID]stack.Component{
@@ -2,7 +2,14 @@ package stacks
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
 
+	"github.com/ksctl/ka/internal/apps"
+	"github.com/ksctl/ka/internal/mirror"
 	gitOpsStandard "github.com/ksctl/ka/internal/stacks/gitops"
 	meshStandard "github.com/ksctl/ka/internal/stacks/mesh/standard"
 	monitoringLite "github.com/ksctl/ka/internal/stacks/monitoring/lite"
@@ -10,6 +17,7 @@ import (
 	spinkubeStandard "github.com/ksctl/ka/internal/stacks/wasm/spinkube"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 	ksctlErrors "github.com/ksctl/ksctl/v2/pkg/errors"
+	"github.com/ksctl/ksctl/v2/pkg/helm"
 	"github.com/ksctl/ksctl/v2/pkg/logger"
 )
 
@@ -21,6 +29,42 @@ var stackManifests = map[stack.ID]func(stack.ApplicationParams) (stack.Applicati
 	spinkubeStandard.SKU: spinkubeStandard.SpinkubeStandard,
 }
 
+// stackOverridings holds the components of every stack along with the overrides
+// each of them accepts, so a Stack can be validated without resolving versions.
+var stackOverridings = map[stack.ID]map[stack.ComponentID]apps.OverridesSchema{
+	gitOpsStandard.SKU:   gitOpsStandard.Overridings,
+	monitoringLite.SKU:   monitoringLite.Overridings,
+	meshStandard.SKU:     meshStandard.Overridings,
+	kwasmPlus.SKU:        kwasmPlus.Overridings,
+	spinkubeStandard.SKU: spinkubeStandard.Overridings,
+}
+
+// stackDependencies holds what every component of a stack needs installed
+// before it, stacks missing here install one component after the other.
+var stackDependencies = map[stack.ID]map[stack.ComponentID][]stack.ComponentID{
+	gitOpsStandard.SKU:   gitOpsStandard.Dependencies,
+	monitoringLite.SKU:   monitoringLite.Dependencies,
+	meshStandard.SKU:     meshStandard.Dependencies,
+	kwasmPlus.SKU:        kwasmPlus.Dependencies,
+	spinkubeStandard.SKU: spinkubeStandard.Dependencies,
+}
+
+// stackPins holds the versions every stack renders besides the ones of its
+// components, which get pinned on the Stack along with them.
+var stackPins = map[stack.ID][]apps.VersionPin{
+	spinkubeStandard.SKU: spinkubeStandard.Pins,
+}
+
+// sharedMirror is where the components get their charts and manifests from in
+// air-gapped clusters, they go upstream without one.
+var sharedMirror *mirror.Mirror
+
+// UseMirror points the components of every stack rendered from now on at the
+// mirror, nil goes back upstream.
+func UseMirror(m *mirror.Mirror) {
+	sharedMirror = m
+}
+
 func Get(ctx context.Context, log logger.Logger, stkID string) (func(stack.ApplicationParams) (stack.ApplicationStack, error), error) {
 	fn, ok := stackManifests[stack.ID(stkID)]
 	if !ok {
@@ -29,12 +73,91 @@ func Get(ctx context.Context, log logger.Logger, stkID string) (func(stack.Appli
 			log.NewError(ctx, "appStack not found", "stkId", stkID),
 		)
 	}
+	if m := sharedMirror; m != nil {
+		return func(params stack.ApplicationParams) (stack.ApplicationStack, error) {
+			stk, err := fn(params)
+			if err != nil {
+				return stk, err
+			}
+			return m.Rewrite(stk)
+		}, nil
+	}
 	return fn, nil
 }
 
+func GetComponentOverridings(stkID string) (map[stack.ComponentID]apps.OverridesSchema, bool) {
+	v, ok := stackOverridings[stack.ID(stkID)]
+	return v, ok
+}
+
+func GetVersionPins(stkID string) []apps.VersionPin {
+	return stackPins[stack.ID(stkID)]
+}
+
+func GetStackIDs() []string {
+	ids := make([]string, 0, len(stackManifests))
+	for k := range stackManifests {
+		ids = append(ids, string(k))
+	}
+	slices.Sort(ids)
+	return ids
+}
+
 func GetComponentVersionOverriding(component stack.Component) string {
 	if component.HandlerType == stack.ComponentTypeKubectl {
 		return component.Kubectl.Version
 	}
 	return component.Helm.Charts[0].Version
 }
+
+// GetComponentHash fingerprints everything which ends up deployed for the
+// component (chart values, manifest urls, namespaces and versions), so a change
+// to any of its overrides can be detected on an installed component.
+func GetComponentHash(component stack.Component) (string, error) {
+	raw, err := json.Marshal(fingerprint(component))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fingerprint leaves out where the charts and manifests of the component are
+// fetched from, keeping which ones they are, so that moving the stacks to or
+// from a mirror does not redeploy every component.
+func fingerprint(component stack.Component) stack.Component {
+	if component.Helm != nil {
+		app := *component.Helm
+		app.RepoName, app.RepoUrl = "", ""
+		app.Charts = make([]helm.ChartOptions, 0, len(component.Helm.Charts))
+		for _, chart := range component.Helm.Charts {
+			chart.Name, chart.ChartRef = mirror.ChartName(chart), ""
+			app.Charts = append(app.Charts, chart)
+		}
+		component.Helm = &app
+	}
+	if component.Kubectl != nil {
+		app := *component.Kubectl
+		app.Urls = make([]string, 0, len(component.Kubectl.Urls))
+		for _, u := range component.Kubectl.Urls {
+			app.Urls = append(app.Urls, manifestPath(u))
+		}
+		component.Kubectl = &app
+	}
+	return component
+}
+
+// manifestPath is where the manifest is kept in the mirror, whether the url
+// points upstream or at the mirror already.
+func manifestPath(u string) string {
+	if m := sharedMirror; m != nil {
+		if p, ok := strings.CutPrefix(u, m.Manifests+"/"); ok {
+			return p
+		}
+	}
+	p, err := mirror.ManifestPath(u)
+	if err != nil {
+		return u
+	}
+	return p
+}
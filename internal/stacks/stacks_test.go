@@ -0,0 +1,107 @@
+package stacks
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/ksctl/ka/internal/mirror"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/helm"
+	"github.com/ksctl/ksctl/v2/pkg/k8s"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+	"github.com/ksctl/ksctl/v2/pkg/poller"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetComponentHash(t *testing.T) {
+	helmComponent := func(args map[string]any) stack.Component {
+		return stack.Component{
+			HandlerType: stack.ComponentTypeHelm,
+			Helm: &helm.App{
+				RepoUrl:  "https://charts.jetstack.io",
+				RepoName: "jetstack",
+				Charts: []helm.ChartOptions{
+					{
+						Name:        "jetstack/cert-manager",
+						Version:     "1.15.3",
+						ReleaseName: "cert-manager",
+						Namespace:   "cert-manager",
+						Args:        args,
+					},
+				},
+			},
+		}
+	}
+
+	h1, err := GetComponentHash(helmComponent(map[string]any{"a": 1, "b": map[string]any{"c": true, "d": "e"}}))
+	assert.NoError(t, err)
+	h2, err := GetComponentHash(helmComponent(map[string]any{"b": map[string]any{"d": "e", "c": true}, "a": 1}))
+	assert.NoError(t, err)
+	assert.Equal(t, h1, h2, "hash must not depend on map ordering")
+
+	h3, err := GetComponentHash(helmComponent(map[string]any{"a": 2, "b": map[string]any{"c": true, "d": "e"}}))
+	assert.NoError(t, err)
+	assert.NotEqual(t, h1, h3)
+
+	k1, err := GetComponentHash(stack.Component{
+		HandlerType: stack.ComponentTypeKubectl,
+		Kubectl:     &k8s.App{Namespace: "argocd", Urls: []string{"https://example.com/install.yaml"}},
+	})
+	assert.NoError(t, err)
+	k2, err := GetComponentHash(stack.Component{
+		HandlerType: stack.ComponentTypeKubectl,
+		Kubectl:     &k8s.App{Namespace: "argocd", Urls: []string{"https://example.com/core-install.yaml"}},
+	})
+	assert.NoError(t, err)
+	assert.NotEqual(t, k1, k2)
+}
+
+func TestGetUsesMirror(t *testing.T) {
+	poller.InitSharedGithubReleaseFakePoller(func(org, repo string) ([]string, error) {
+		return []string{"1.22.4"}, nil
+	})
+	m, err := mirror.New("http://mirror.local/ka", "")
+	assert.NoError(t, err)
+	UseMirror(m)
+	defer UseMirror(nil)
+
+	fn, err := Get(context.Background(), logger.NewStructuredLogger(-1, os.Stdout), "mesh-standard")
+	assert.NoError(t, err)
+	stk, err := fn(stack.ApplicationParams{})
+	assert.NoError(t, err)
+
+	for _, c := range stk.Components {
+		assert.Equal(t, "http://mirror.local/ka/charts", c.Helm.RepoUrl)
+	}
+}
+
+func TestGetComponentHashIgnoresMirror(t *testing.T) {
+	poller.InitSharedGithubReleaseFakePoller(func(org, repo string) ([]string, error) {
+		return []string{"v1.22.4"}, nil
+	})
+	kl := logger.NewStructuredLogger(-1, os.Stdout)
+	hashes := func() map[string]string {
+		h := map[string]string{}
+		for _, stkID := range GetStackIDs() {
+			fn, err := Get(context.Background(), kl, stkID)
+			assert.NoError(t, err)
+			stk, err := fn(stack.ApplicationParams{})
+			assert.NoError(t, err)
+			for id, c := range stk.Components {
+				h[stkID+"/"+string(id)], err = GetComponentHash(c)
+				assert.NoError(t, err)
+			}
+		}
+		return h
+	}
+
+	upstream := hashes()
+	for _, charts := range []string{"http://mirror.local/ka", "oci://registry.local/ka"} {
+		m, err := mirror.New(charts, "http://mirror.local/ka")
+		assert.NoError(t, err)
+		UseMirror(m)
+		assert.Equal(t, upstream, hashes(), charts)
+	}
+	UseMirror(nil)
+}
@@ -1,6 +1,7 @@
 package kwasm
 
 import (
+	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ka/internal/apps/kwasm"
 	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
 )
@@ -9,6 +10,17 @@ const (
 	SKU stack.ID = "wasm/kwasm-plus"
 )
 
+var Overridings = map[stack.ComponentID]apps.OverridesSchema{
+	kwasm.OperatorSKU: kwasm.OperatorOverridingsSchema,
+	kwasm.RuntimeSKU:  kwasm.RuntimeOverridingsSchema,
+}
+
+// Dependencies installs the runtime once the operator is up.
+var Dependencies = map[stack.ComponentID][]stack.ComponentID{
+	kwasm.OperatorSKU: nil,
+	kwasm.RuntimeSKU:  {kwasm.OperatorSKU},
+}
+
 func KwasmPlus(params stack.ApplicationParams) (stack.ApplicationStack, error) {
 
 	kwasmOperatorComponent, err := kwasm.KwasmOperatorComponent(
@@ -11,13 +11,17 @@ import (
 
 	kwasmPlus "github.com/ksctl/ka/internal/stacks/wasm/kwasm"
 	spinkubeStandard "github.com/ksctl/ka/internal/stacks/wasm/spinkube"
+	"github.com/ksctl/ka/internal/tracing"
 )
 
 func ShouldPerformAdditionalProcessing(stackID stack.ID) bool {
 	return stackID == kwasmPlus.SKU || stackID == spinkubeStandard.SKU
 }
 
-func AfterInstall(ctx context.Context, c client.Client) error {
+func AfterInstall(ctx context.Context, c client.Client) (err error) {
+	ctx, span := tracing.Start(ctx, "wasm.AfterInstall")
+	defer func() { tracing.End(span, err) }()
+
 	l := log.FromContext(ctx)
 	nodes := &corev1.NodeList{}
 	if err := c.List(ctx, nodes, &client.ListOptions{}); err != nil {
@@ -25,6 +29,7 @@ func AfterInstall(ctx context.Context, c client.Client) error {
 	}
 
 	for _, node := range nodes.Items {
+		nodeCtx, nodeSpan := tracing.Start(ctx, "wasm.AnnotateNode", tracing.AttrNode.String(node.Name))
 		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 			l.Info("Annotating node", "targetNodeName", node.Name)
 
@@ -38,7 +43,7 @@ func AfterInstall(ctx context.Context, c client.Client) error {
 			}
 			node.Annotations["kwasm.sh/kwasm-node"] = "true"
 
-			if err := c.Update(ctx, &node, &client.UpdateOptions{}); err != nil {
+			if err := c.Update(nodeCtx, &node, &client.UpdateOptions{}); err != nil {
 				l.Error(err, "Failed to annotate node, retrying", "targetNodeName", node.Name)
 				return err
 			}
@@ -46,6 +51,7 @@ func AfterInstall(ctx context.Context, c client.Client) error {
 
 			return nil
 		})
+		tracing.End(nodeSpan, retryErr)
 		if retryErr != nil {
 			return retryErr
 		}
@@ -0,0 +1,41 @@
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ksctl/ka/internal/tracing"
+)
+
+func TestAfterInstallTracesEveryNode(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(tracing.NewTracerProvider(tracing.Options{}, sdktrace.WithSpanProcessor(recorder)))
+
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b", Annotations: map[string]string{"kwasm.sh/kwasm-node": "true"}}},
+	).Build()
+
+	assert.NoError(t, AfterInstall(context.Background(), c))
+
+	node := &corev1.Node{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: "node-a"}, node))
+	assert.Equal(t, "true", node.Annotations["kwasm.sh/kwasm-node"])
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "wasm.AnnotateNode", spans[0].Name())
+	assert.Equal(t, tracing.AttrNode.String("node-a"), spans[0].Attributes()[0])
+	assert.Equal(t, "wasm.AnnotateNode", spans[1].Name())
+	assert.Equal(t, "wasm.AfterInstall", spans[2].Name())
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
@@ -1,6 +1,7 @@
 package spinkube
 
 import (
+	"github.com/ksctl/ka/internal/apps"
 	"github.com/ksctl/ka/internal/apps/certmanager"
 	"github.com/ksctl/ka/internal/apps/kwasm"
 	"github.com/ksctl/ka/internal/apps/spinkube"
@@ -11,6 +12,38 @@ const (
 	SKU stack.ID = "wasm/spinkube-standard"
 )
 
+var Overridings = map[stack.ComponentID]apps.OverridesSchema{
+	certmanager.SKU:                  certmanager.OverridingsSchema,
+	kwasm.OperatorSKU:                spinkube.KwasmOperatorOverridingsSchema,
+	spinkube.OperatorCrdSKU:          spinkube.OverridingsSchema,
+	spinkube.OperatorRuntimeClassSKU: spinkube.OverridingsSchema,
+	spinkube.OperatorShimExecutorSKU: spinkube.OverridingsSchema,
+	spinkube.OperatorSKU:             spinkube.OperatorOverridingsSchema,
+}
+
+// Pins keeps the shim the kwasm operator installs on the nodes at the version
+// it got first, a new shim release would otherwise redeploy the operator.
+var Pins = []apps.VersionPin{
+	{Component: kwasm.OperatorSKU, Key: spinkube.ShimVersionKey, Resolve: spinkube.ShimVersion},
+}
+
+// Dependencies holds the spin operator back until cert-manager serves its
+// webhook and the CRDs, runtime class and shim executor it relies on exist.
+var Dependencies = map[stack.ComponentID][]stack.ComponentID{
+	certmanager.SKU:                  nil,
+	kwasm.OperatorSKU:                nil,
+	spinkube.OperatorCrdSKU:          nil,
+	spinkube.OperatorRuntimeClassSKU: nil,
+	spinkube.OperatorShimExecutorSKU: {spinkube.OperatorCrdSKU},
+	spinkube.OperatorSKU: {
+		certmanager.SKU,
+		kwasm.OperatorSKU,
+		spinkube.OperatorCrdSKU,
+		spinkube.OperatorRuntimeClassSKU,
+		spinkube.OperatorShimExecutorSKU,
+	},
+}
+
 func SpinkubeStandard(params stack.ApplicationParams) (stack.ApplicationStack, error) {
 
 	certManagerComponent, err := certmanager.CertManagerComponent(
@@ -20,14 +53,13 @@ func SpinkubeStandard(params stack.ApplicationParams) (stack.ApplicationStack, e
 		return stack.ApplicationStack{}, err
 	}
 
-	if err := spinkube.GetSpinKubeStackSpecificKwasmOverrides(
-		params.ComponentParams[kwasm.OperatorSKU]); err != nil {
+	kwasmOverrides, err := spinkube.GetSpinKubeStackSpecificKwasmOverrides(
+		params.ComponentParams[kwasm.OperatorSKU])
+	if err != nil {
 		return stack.ApplicationStack{}, err
 	}
 
-	kwasmOperatorComponent, err := kwasm.KwasmOperatorComponent(
-		params.ComponentParams[kwasm.OperatorSKU],
-	)
+	kwasmOperatorComponent, err := kwasm.KwasmOperatorComponent(kwasmOverrides)
 	if err != nil {
 		return stack.ApplicationStack{}, err
 	}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the spans of the manager.
+const TracerName string = "github.com/ksctl/ka"
+
+const (
+	// AttrStack is the name of the Stack object.
+	AttrStack = attribute.Key("ka.stack")
+	// AttrStackID is the stack the Stack object installs (spec.stackName).
+	AttrStackID       = attribute.Key("ka.stack.id")
+	AttrComponent     = attribute.Key("ka.component")
+	AttrVersion       = attribute.Key("ka.component.version")
+	AttrAction        = attribute.Key("ka.component.action")
+	AttrHelmRelease   = attribute.Key("helm.release")
+	AttrHelmChart     = attribute.Key("helm.chart")
+	AttrHelmNamespace = attribute.Key("helm.namespace")
+	AttrHelmChartVer  = attribute.Key("helm.chart.version")
+	AttrManifestURLs  = attribute.Key("kubectl.urls")
+	AttrNode          = attribute.Key("k8s.node.name")
+)
+
+const DefaultServiceName string = "ka-controller-manager"
+
+// Options configures the export of the spans, tracing is disabled without an
+// endpoint.
+type Options struct {
+	// Endpoint is the OTLP/gRPC collector, either host:port or a URL.
+	Endpoint string
+	Insecure bool
+	// SampleRatio is the share of the traces started by the manager which get
+	// sampled, traces of sampled parents always are.
+	SampleRatio float64
+	ServiceName string
+}
+
+// OptionsFromEnv picks up the standard OTLP exporter environment.
+func OptionsFromEnv() Options {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if len(endpoint) == 0 {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if len(serviceName) == 0 {
+		serviceName = DefaultServiceName
+	}
+	return Options{
+		Endpoint:    endpoint,
+		Insecure:    strings.EqualFold(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"), "true"),
+		SampleRatio: 1,
+		ServiceName: serviceName,
+	}
+}
+
+// Setup installs the tracer provider exporting to the collector, the returned
+// func flushes the pending spans on shutdown.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if len(opts.Endpoint) == 0 {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var clientOpts []otlptracegrpc.Option
+	if strings.Contains(opts.Endpoint, "://") {
+		clientOpts = append(clientOpts, otlptracegrpc.WithEndpointURL(opts.Endpoint))
+	} else {
+		clientOpts = append(clientOpts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+	}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := NewTracerProvider(opts, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider builds the provider the manager traces with, the options
+// add where the spans go (e.g. a tracetest.SpanRecorder in tests).
+func NewTracerProvider(opts Options, tpOpts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	serviceName := opts.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = DefaultServiceName
+	}
+	ratio := opts.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	}, tpOpts...)...)
+}
+
+type attributesKey struct{}
+
+// ContextWithAttributes hands attributes down to every span started from the
+// context, the executors only get to know the stack and component this way.
+func ContextWithAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	inherited, _ := ctx.Value(attributesKey{}).([]attribute.KeyValue)
+	return context.WithValue(ctx, attributesKey{}, append(append([]attribute.KeyValue{}, inherited...), attrs...))
+}
+
+// Start begins a span of the manager carrying the attributes of the context
+// along with the given ones.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	inherited, _ := ctx.Value(attributesKey{}).([]attribute.KeyValue)
+	return otel.Tracer(TracerName).Start(ctx, name,
+		trace.WithAttributes(append(append([]attribute.KeyValue{}, inherited...), attrs...)...))
+}
+
+// End records the outcome of the operation on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartInheritsContextAttributes(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(NewTracerProvider(Options{}, sdktrace.WithSpanProcessor(recorder)))
+
+	ctx := ContextWithAttributes(context.Background(), AttrStack.String("spinkube"))
+	ctx, parent := Start(ctx, "Reconcile")
+	ctx = ContextWithAttributes(ctx, AttrComponent.String("spinkube-operator"), AttrVersion.String("0.4.0"))
+	_, child := Start(ctx, "helm.deploy", AttrHelmRelease.StringSlice([]string{"spin-operator"}))
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	assert.Equal(t, "helm.deploy", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		AttrStack.String("spinkube"),
+		AttrComponent.String("spinkube-operator"),
+		AttrVersion.String("0.4.0"),
+		AttrHelmRelease.StringSlice([]string{"spin-operator"}),
+	}, spans[0].Attributes())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Len(t, spans[0].Events(), 1)
+
+	assert.Equal(t, "Reconcile", spans[1].Name())
+	assert.Equal(t, []attribute.KeyValue{AttrStack.String("spinkube")}, spans[1].Attributes())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "collector:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "true")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+
+	opts := OptionsFromEnv()
+	assert.Equal(t, "collector:4317", opts.Endpoint)
+	assert.True(t, opts.Insecure)
+	assert.Equal(t, DefaultServiceName, opts.ServiceName)
+}
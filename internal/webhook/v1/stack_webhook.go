@@ -0,0 +1,312 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/apps"
+	"github.com/ksctl/ka/internal/schedule"
+	"github.com/ksctl/ka/internal/stacks"
+	"github.com/ksctl/ksctl/v2/pkg/apps/stack"
+	"github.com/ksctl/ksctl/v2/pkg/logger"
+)
+
+// log is for logging in this package.
+var stacklog = logf.Log.WithName("stack-resource")
+
+const versionKey string = "version"
+
+// SetupStackWebhookWithManager registers the webhook for Stack in the manager.
+func SetupStackWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&appv1.Stack{}).
+		WithValidator(&StackCustomValidator{}).
+		WithDefaulter(&StackCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-app-ksctl-com-v1-stack,mutating=true,failurePolicy=fail,sideEffects=None,groups=app.ksctl.com,resources=stacks,verbs=create,versions=v1,name=mstack-v1.kb.io,admissionReviewVersions=v1,timeoutSeconds=30
+
+// StackCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind Stack when those are created.
+//
+// Every component version which would otherwise float ("latest" or unset) is resolved once and written
+// back into spec.overrides, so that applying the same Stack twice results in the same install.
+type StackCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &StackCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Stack.
+func (d *StackCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	stk, ok := obj.(*appv1.Stack)
+	if !ok {
+		return fmt.Errorf("expected a Stack object but got %T", obj)
+	}
+	stacklog.Info("Defaulting for Stack", "name", stk.GetName())
+
+	pinned, err := pinComponentVersions(ctx, stk)
+	if err != nil {
+		// Version resolution depends on upstream release APIs, an outage there must not
+		// block Stack creation; the controller resolves the versions while reconciling.
+		stacklog.Error(err, "Unable to pin component versions", "name", stk.GetName())
+		return nil
+	}
+	if len(pinned) > 0 {
+		stacklog.Info("Pinned component versions", "name", stk.GetName(), "versions", pinned)
+	}
+	return nil
+}
+
+func pinComponentVersions(ctx context.Context, stk *appv1.Stack) (map[string]string, error) {
+	components, ok := stacks.GetComponentOverridings(stk.Spec.StackName)
+	if !ok {
+		return nil, nil // rejected by the validating webhook
+	}
+
+	overrides := map[string]map[string]any{}
+	if stk.Spec.Overrides != nil {
+		if err := json.Unmarshal(stk.Spec.Overrides.Raw, &overrides); err != nil {
+			return nil, nil // rejected by the validating webhook
+		}
+	}
+
+	// The stack manifests are allowed to mutate the overrides they are given,
+	// so resolve against a copy and only write back the versions.
+	params := stack.ApplicationParams{ComponentParams: map[stack.ComponentID]stack.ComponentOverrides{}}
+	for id, o := range overrides {
+		params.ComponentParams[stack.ComponentID(id)] = maps.Clone(o)
+	}
+
+	appStk, err := stacks.Get(ctx, logger.NewStructuredLogger(-1, os.Stdout), stk.Spec.StackName)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := appStk(params)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := map[string]string{}
+	for _, componentId := range manifest.StkDepsIdx {
+		if slices.Contains(stk.Spec.DisableComponents, string(componentId)) {
+			continue
+		}
+		if _, ok := components[componentId][versionKey]; !ok {
+			continue
+		}
+		if v, ok := overrides[string(componentId)][versionKey].(string); ok && v != "latest" {
+			continue
+		}
+		if stk.Spec.Updates.ChannelOf(string(componentId)) != appv1.ChannelPinned && components[componentId].ResolvesVersions() {
+			continue // upgraded along its channel
+		}
+		component, ok := manifest.Components[componentId]
+		if !ok {
+			continue
+		}
+
+		ver := stacks.GetComponentVersionOverriding(component)
+		if ver == "" || ver == "latest" || ver == "stable" {
+			continue
+		}
+
+		if overrides[string(componentId)] == nil {
+			overrides[string(componentId)] = map[string]any{}
+		}
+		overrides[string(componentId)][versionKey] = ver
+		pinned[string(componentId)] = ver
+	}
+
+	for _, pin := range stacks.GetVersionPins(stk.Spec.StackName) {
+		if slices.Contains(stk.Spec.DisableComponents, string(pin.Component)) {
+			continue
+		}
+		if v, ok := overrides[string(pin.Component)][pin.Key].(string); ok && v != "latest" {
+			continue
+		}
+		ver, err := pin.Resolve(overrides[string(pin.Component)])
+		if err != nil {
+			return nil, err
+		}
+
+		if overrides[string(pin.Component)] == nil {
+			overrides[string(pin.Component)] = map[string]any{}
+		}
+		overrides[string(pin.Component)][pin.Key] = ver
+		pinned[string(pin.Component)+"."+pin.Key] = ver
+	}
+
+	if len(pinned) == 0 {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	stk.Spec.Overrides = &apiextensionsv1.JSON{Raw: raw}
+
+	return pinned, nil
+}
+
+// +kubebuilder:webhook:path=/validate-app-ksctl-com-v1-stack,mutating=false,failurePolicy=fail,sideEffects=None,groups=app.ksctl.com,resources=stacks,verbs=create;update,versions=v1,name=vstack-v1.kb.io,admissionReviewVersions=v1
+
+// StackCustomValidator struct is responsible for validating the Stack resource
+// when it is created, updated, or deleted.
+type StackCustomValidator struct{}
+
+var _ webhook.CustomValidator = &StackCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Stack.
+func (v *StackCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	stk, ok := obj.(*appv1.Stack)
+	if !ok {
+		return nil, fmt.Errorf("expected a Stack object but got %T", obj)
+	}
+	stacklog.Info("Validation for Stack upon creation", "name", stk.GetName())
+
+	return nil, validateStack(stk)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Stack.
+func (v *StackCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	stk, ok := newObj.(*appv1.Stack)
+	if !ok {
+		return nil, fmt.Errorf("expected a Stack object for the newObj but got %T", newObj)
+	}
+	stacklog.Info("Validation for Stack upon update", "name", stk.GetName())
+
+	// Objects being finalized must stay editable so the finalizer can be removed.
+	if !stk.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	return nil, validateStack(stk)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Stack.
+func (v *StackCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateStack(stk *appv1.Stack) error {
+	allErrs := validateStackSpec(&stk.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(appv1.GroupVersion.WithKind("Stack").GroupKind(), stk.Name, allErrs)
+}
+
+func validateStackSpec(spec *appv1.StackSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	components, ok := stacks.GetComponentOverridings(spec.StackName)
+	if !ok {
+		return append(allErrs, field.NotSupported(fldPath.Child("stackName"), spec.StackName, stacks.GetStackIDs()))
+	}
+
+	componentIDs := make([]string, 0, len(components))
+	for id := range components {
+		componentIDs = append(componentIDs, string(id))
+	}
+	slices.Sort(componentIDs)
+
+	for i, id := range spec.DisableComponents {
+		if _, ok := components[stack.ComponentID(id)]; !ok {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("disableComponents").Index(i), id, componentIDs))
+		}
+	}
+
+	allErrs = append(allErrs, validateUpdatePolicy(spec.Updates, components, componentIDs, fldPath.Child("updates"))...)
+
+	if spec.Overrides == nil {
+		return allErrs
+	}
+
+	overridesPath := fldPath.Child("overrides")
+	var overrides map[string]map[string]any
+	if err := json.Unmarshal(spec.Overrides.Raw, &overrides); err != nil {
+		return append(allErrs, field.Invalid(overridesPath, string(spec.Overrides.Raw),
+			"must be an object keyed by component id, each holding an object of overrides"))
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(overrides)) {
+		schema, ok := components[stack.ComponentID(id)]
+		if !ok {
+			allErrs = append(allErrs, field.NotSupported(overridesPath.Key(id), id, componentIDs))
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(overrides[id])) {
+			val := overrides[id][key]
+			if err := schema.Check(key, val); err != nil {
+				allErrs = append(allErrs, field.Invalid(overridesPath.Key(id).Key(key), val, err.Error()))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func validateUpdatePolicy(
+	updates *appv1.UpdatePolicy,
+	components map[stack.ComponentID]apps.OverridesSchema,
+	componentIDs []string,
+	fldPath *field.Path,
+) field.ErrorList {
+	var allErrs field.ErrorList
+	if updates == nil {
+		return allErrs
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(updates.Channels)) {
+		schema, ok := components[stack.ComponentID(id)]
+		if !ok {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("channels").Key(id), id, componentIDs))
+			continue
+		}
+		if channel := updates.Channels[id]; channel != appv1.ChannelPinned && !schema.ResolvesVersions() {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("channels").Key(id), channel,
+				"the component has no version source to follow a channel with"))
+		}
+	}
+	if updates.CheckInterval != nil && updates.CheckInterval.Duration < time.Minute {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("checkInterval"), updates.CheckInterval.Duration.String(),
+			"must be at least a minute"))
+	}
+	if w := updates.MaintenanceWindow; w != nil {
+		if _, err := schedule.NewWindow(w.Schedule, w.Duration.Duration, w.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("maintenanceWindow"), w.Schedule, err.Error()))
+		}
+	}
+	return allErrs
+}
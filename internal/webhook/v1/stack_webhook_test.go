@@ -0,0 +1,266 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ksctl/v2/pkg/poller"
+)
+
+var _ = Describe("Stack Webhook", func() {
+	var (
+		obj       *appv1.Stack
+		oldObj    *appv1.Stack
+		validator StackCustomValidator
+		defaulter StackCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		obj = &appv1.Stack{}
+		oldObj = &appv1.Stack{}
+		validator = StackCustomValidator{}
+		defaulter = StackCustomDefaulter{}
+
+		poller.InitSharedGithubReleaseFakePoller(func(org, repo string) ([]string, error) {
+			switch org + " " + repo {
+			case "istio istio":
+				return []string{"1.22.4", "1.21.0"}, nil
+			case "argoproj argo-rollouts":
+				return []string{"v1.7.2", "v1.6.0"}, nil
+			case "cert-manager cert-manager":
+				return []string{"v1.15.3"}, nil
+			case "spinkube spin-operator":
+				return []string{"v0.4.0"}, nil
+			case "spinkube containerd-shim-spin":
+				return []string{"v0.15.1"}, nil
+			}
+			return []string{"v0.0.1"}, nil
+		})
+	})
+
+	overridesOf := func(stk *appv1.Stack) map[string]map[string]any {
+		GinkgoHelper()
+		o := map[string]map[string]any{}
+		Expect(stk.Spec.Overrides).NotTo(BeNil())
+		Expect(json.Unmarshal(stk.Spec.Overrides.Raw, &o)).To(Succeed())
+		return o
+	}
+
+	Context("When creating Stack under Defaulting Webhook", func() {
+		It("Should pin the resolved version of every component which follows latest", func() {
+			obj.Spec.StackName = "mesh-standard"
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(overridesOf(obj)).To(HaveKeyWithValue("istio", HaveKeyWithValue("version", "1.22.4")))
+
+			obj = &appv1.Stack{}
+			obj.Spec.StackName = "mesh-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"istio":{"version":"latest"}}`)}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(overridesOf(obj)).To(HaveKeyWithValue("istio", HaveKeyWithValue("version", "1.22.4")))
+		})
+
+		It("Should keep versions and other overrides given by the user", func() {
+			obj.Spec.StackName = "gitops-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argorollouts":{"version":"v1.6.0","namespaceInstall":true}}`)}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+
+			o := overridesOf(obj)
+			Expect(o["argorollouts"]).To(Equal(map[string]any{"version": "v1.6.0", "namespaceInstall": true}))
+			Expect(o).NotTo(HaveKey("argocd"), "argocd follows the stable branch which is not a release")
+		})
+
+		It("Should keep version constraints, which are resolved on every reconcile", func() {
+			obj.Spec.StackName = "mesh-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"istio":{"version":"~1.21"}}`)}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(overridesOf(obj)).To(HaveKeyWithValue("istio", HaveKeyWithValue("version", "~1.21")))
+		})
+
+		It("Should not pin disabled components or components without a version override", func() {
+			obj.Spec.StackName = "wasm/spinkube-standard"
+			obj.Spec.DisableComponents = []string{"cert-manager"}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+
+			o := overridesOf(obj)
+			Expect(o).NotTo(HaveKey("cert-manager"))
+			Expect(o["kwasm-operator"]).NotTo(HaveKey("version"))
+			Expect(o).To(HaveKeyWithValue("spinkube-operator", HaveKeyWithValue("version", "0.4.0")))
+			Expect(o).To(HaveKeyWithValue("spinkube-operator-crd", HaveKeyWithValue("version", "v0.4.0")))
+
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should pin the shim the kwasm operator of spinkube installs", func() {
+			obj.Spec.StackName = "wasm/spinkube-standard"
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(overridesOf(obj)).To(HaveKeyWithValue("kwasm-operator", Equal(map[string]any{"shimVersion": "v0.15.1"})))
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj = &appv1.Stack{}
+			obj.Spec.StackName = "wasm/spinkube-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"kwasm-operator":{"shimVersion":"v0.14.0"}}`)}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(overridesOf(obj)).To(HaveKeyWithValue("kwasm-operator", Equal(map[string]any{"shimVersion": "v0.14.0"})))
+
+			obj = &appv1.Stack{}
+			obj.Spec.StackName = "wasm/spinkube-standard"
+			obj.Spec.DisableComponents = []string{"kwasm-operator"}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(overridesOf(obj)).NotTo(HaveKey("kwasm-operator"))
+		})
+
+		It("Should not pin components which follow an update channel", func() {
+			obj.Spec.StackName = "mesh-standard"
+			obj.Spec.Updates = &appv1.UpdatePolicy{Channels: map[string]appv1.UpdateChannel{"istio": appv1.ChannelPatch}}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Overrides).To(BeNil(), "istio follows the patch channel")
+
+			obj.Spec.Updates = &appv1.UpdatePolicy{Channel: appv1.ChannelMinor, Channels: map[string]appv1.UpdateChannel{"istio": appv1.ChannelPinned}}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(overridesOf(obj)).To(HaveKeyWithValue("istio", HaveKeyWithValue("version", "1.22.4")))
+		})
+
+		It("Should leave unknown stacks to the validating webhook", func() {
+			obj.Spec.StackName = "unknown"
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Overrides).To(BeNil())
+		})
+	})
+
+	Context("When creating or updating Stack under Validating Webhook", func() {
+		It("Should admit a known stack without overrides", func() {
+			obj.Spec.StackName = "gitops-standard"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny an unknown stackName", func() {
+			obj.Spec.StackName = "gitops-standrad"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.stackName"))
+		})
+
+		It("Should deny disabling a component which is not part of the stack", func() {
+			obj.Spec.StackName = "wasm/spinkube-standard"
+			obj.Spec.DisableComponents = []string{"cert-manager", "istio"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.disableComponents[1]"))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.disableComponents[0]"))
+		})
+
+		It("Should deny overrides for components outside the stack", func() {
+			obj.Spec.StackName = "monitoring-lite"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"istio":{"version":"1.22.4"}}`)}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.overrides[istio]"))
+		})
+
+		It("Should deny unknown override keys and values of the wrong type", func() {
+			obj.Spec.StackName = "gitops-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"noUI":"true","nameSpace":"argo"}}`)}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.overrides[argocd][noUI]"))
+			Expect(err.Error()).To(ContainSubstring("spec.overrides[argocd][nameSpace]"))
+		})
+
+		It("Should deny version constraints for components without a version source", func() {
+			obj.Spec.StackName = "gitops-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"version":"~2.10"},"argorollouts":{"version":"~1.7"}}`)}
+			obj.Spec.Updates = &appv1.UpdatePolicy{Channels: map[string]appv1.UpdateChannel{
+				"argocd":       appv1.ChannelPatch,
+				"argorollouts": appv1.ChannelPatch,
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.overrides[argocd][version]"))
+			Expect(err.Error()).To(ContainSubstring("spec.updates.channels[argocd]"))
+			Expect(err.Error()).NotTo(ContainSubstring("argorollouts"))
+
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"version":"v2.10.4"}}`)}
+			obj.Spec.Updates = nil
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny overrides which are not keyed by component", func() {
+			obj.Spec.StackName = "gitops-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":"v2.13.0"}`)}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.overrides"))
+		})
+
+		It("Should deny update channels of unknown components and invalid maintenance windows", func() {
+			obj.Spec.StackName = "mesh-standard"
+			obj.Spec.Updates = &appv1.UpdatePolicy{
+				Channels:      map[string]appv1.UpdateChannel{"argocd": appv1.ChannelPatch},
+				CheckInterval: &metav1.Duration{Duration: 10 * time.Second},
+				MaintenanceWindow: &appv1.MaintenanceWindow{
+					Schedule: "0 25 * * sat",
+					Duration: metav1.Duration{Duration: time.Hour},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.updates.channels[argocd]"))
+			Expect(err.Error()).To(ContainSubstring("spec.updates.checkInterval"))
+			Expect(err.Error()).To(ContainSubstring("spec.updates.maintenanceWindow"))
+
+			obj.Spec.Updates = &appv1.UpdatePolicy{MaintenanceWindow: &appv1.MaintenanceWindow{
+				Schedule: "0 2 * * sat",
+				Duration: metav1.Duration{Duration: time.Hour},
+				TimeZone: "Europe/Atlantis",
+			}}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.updates.maintenanceWindow"))
+		})
+
+		It("Should admit an update policy with a maintenance window", func() {
+			obj.Spec.StackName = "mesh-standard"
+			obj.Spec.Updates = &appv1.UpdatePolicy{
+				Channel:       appv1.ChannelMinor,
+				Channels:      map[string]appv1.UpdateChannel{"istio": appv1.ChannelPatch},
+				CheckInterval: &metav1.Duration{Duration: 30 * time.Minute},
+				MaintenanceWindow: &appv1.MaintenanceWindow{
+					Schedule: "0 2 * * sat,sun",
+					Duration: metav1.Duration{Duration: 3 * time.Hour},
+					TimeZone: "Europe/Berlin",
+				},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should admit valid overrides on update", func() {
+			oldObj.Spec.StackName = "gitops-standard"
+			obj.Spec.StackName = "gitops-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"noUI":true,"version":"v2.13.0"}}`)}
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,109 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+	"github.com/ksctl/ka/internal/policy"
+)
+
+// log is for logging in this package.
+var stackinstancelog = logf.Log.WithName("stackinstance-resource")
+
+// SetupStackInstanceWebhookWithManager registers the webhook for StackInstance in the manager.
+func SetupStackInstanceWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&appv1.StackInstance{}).
+		WithValidator(&StackInstanceCustomValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-app-ksctl-com-v1-stackinstance,mutating=false,failurePolicy=fail,sideEffects=None,groups=app.ksctl.com,resources=stackinstances,verbs=create;update,versions=v1,name=vstackinstance-v1.kb.io,admissionReviewVersions=v1
+
+// StackInstanceCustomValidator struct is responsible for validating the StackInstance resource
+// when it is created or updated, the StackPolicies of its namespace have to allow it.
+type StackInstanceCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &StackInstanceCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type StackInstance.
+func (v *StackInstanceCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	instance, ok := obj.(*appv1.StackInstance)
+	if !ok {
+		return nil, fmt.Errorf("expected a StackInstance object but got %T", obj)
+	}
+	stackinstancelog.Info("Validation for StackInstance upon creation", "name", instance.GetName(), "namespace", instance.GetNamespace())
+
+	return nil, v.validateStackInstance(ctx, instance)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type StackInstance.
+func (v *StackInstanceCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	instance, ok := newObj.(*appv1.StackInstance)
+	if !ok {
+		return nil, fmt.Errorf("expected a StackInstance object for the newObj but got %T", newObj)
+	}
+	stackinstancelog.Info("Validation for StackInstance upon update", "name", instance.GetName(), "namespace", instance.GetNamespace())
+
+	// Objects being finalized must stay editable so the finalizer can be removed.
+	if !instance.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	return nil, v.validateStackInstance(ctx, instance)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type StackInstance.
+func (v *StackInstanceCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *StackInstanceCustomValidator) validateStackInstance(ctx context.Context, instance *appv1.StackInstance) error {
+	gk := appv1.GroupVersion.WithKind("StackInstance").GroupKind()
+
+	specPath := field.NewPath("spec")
+	if allErrs := validateStackSpec(&instance.Spec.StackSpec, specPath); len(allErrs) != 0 {
+		return apierrors.NewInvalid(gk, instance.Name, allErrs)
+	}
+
+	ns := &corev1.Namespace{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Name: instance.Namespace}, ns); err != nil {
+		return err
+	}
+	policies := &appv1.StackPolicyList{}
+	if err := v.Client.List(ctx, policies); err != nil {
+		return err
+	}
+	if _, err := policy.Admit(policies.Items, ns, instance.Spec.StackSpec); err != nil {
+		return apierrors.NewForbidden(appv1.GroupVersion.WithResource("stackinstances").GroupResource(), instance.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appv1 "github.com/ksctl/ka/api/v1"
+)
+
+var _ = Describe("StackInstance Webhook", func() {
+	var (
+		obj       *appv1.StackInstance
+		validator StackInstanceCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &appv1.StackInstance{ObjectMeta: metav1.ObjectMeta{Name: "gitops", Namespace: "team-a"}}
+
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(appv1.AddToScheme(s)).To(Succeed())
+		validator = StackInstanceCustomValidator{Client: fake.NewClientBuilder().WithScheme(s).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+			&appv1.StackPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+				Spec: appv1.StackPolicySpec{
+					Namespaces: []string{"team-a"},
+					Stacks: []appv1.AllowedStack{{
+						StackName: "gitops-standard",
+						Overrides: map[string][]string{"argocd": {"version"}},
+					}},
+				},
+			},
+		).Build()}
+	})
+
+	Context("When creating or updating StackInstance under Validating Webhook", func() {
+		It("Should admit a stack allowed in the namespace", func() {
+			obj.Spec.StackName = "gitops-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"version":"v2.13.0"}}`)}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a stack no policy allows in the namespace", func() {
+			obj.Spec.StackName = "monitoring-lite"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		})
+
+		It("Should deny overrides the policy doesn't allow", func() {
+			obj.Spec.StackName = "gitops-standard"
+			obj.Spec.Overrides = &apiextensionsv1.JSON{Raw: []byte(`{"argocd":{"noUI":true}}`)}
+			_, err := validator.ValidateUpdate(ctx, obj.DeepCopy(), obj)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("override noUI of component argocd is not allowed"))
+		})
+
+		It("Should deny an invalid spec before looking at the policies", func() {
+			obj.Spec.StackName = "gitops-standrad"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+})